@@ -0,0 +1,135 @@
+// Package anomaly detects the days whose spend stands out from the days before them.
+package anomaly
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+// The methods scoring how far a day is from the trailing window.
+const (
+	// MAD scores a day by its modified z-score, its distance to the median in median absolute deviations,
+	// which a few anomalous days in the window don't skew.
+	MAD = "mad"
+	// ZScore scores a day by its distance to the mean in standard deviations.
+	ZScore = "zscore"
+)
+
+// DefaultThresholds are the scores above which a day is anomalous, by method.
+var DefaultThresholds = map[string]float64{
+	MAD:    3.5,
+	ZScore: 3,
+}
+
+// DefaultMinDelta is the difference in USD with the expected spend below which a day isn't anomalous,
+// whatever its score.
+const DefaultMinDelta = 1.0
+
+// madScale makes the median absolute deviation comparable with a standard deviation for normal data.
+const madScale = 0.6745
+
+// meanADScale makes the mean absolute deviation comparable with a standard deviation for normal data.
+const meanADScale = 0.7979
+
+// minSpread is the spread of a window whose spend doesn't vary at all, a cent, so that its score stays finite.
+const minSpread = 0.01
+
+// minWindow is the number of days the window needs to hold before any day is scored.
+const minWindow = 3
+
+// Day is the spend of a day.
+type Day struct {
+	Date  time.Time
+	Spend float64
+}
+
+// Anomaly is a day whose spend is far from the spend expected from the window.
+type Anomaly struct {
+	Day Day
+	// Expected is the median or mean of the window, depending on the method.
+	Expected float64
+	// Score is how far the day is from the expected spend, positive above it and negative below it.
+	Score float64
+	// Window is the number of days the day was compared with.
+	Window int
+}
+
+// Delta returns how much the spend of the day differs from the expected spend.
+func (o Anomaly) Delta() float64 {
+	return o.Day.Spend - o.Expected
+}
+
+// Detect scores each day against the window of days before it with the method, in chronological order,
+// and returns the days whose score is above the threshold in either direction,
+// and whose spend differs from the expected one by at least minDelta.
+func Detect(days []Day, window int, threshold, minDelta float64, method string) []Anomaly {
+	anomalies := make([]Anomaly, 0)
+	for i := range days {
+		start := i - window
+		if start < 0 {
+			start = 0
+		}
+		if i-start < minWindow {
+			continue
+		}
+		spends := make([]float64, 0, i-start)
+		for _, day := range days[start:i] {
+			spends = append(spends, day.Spend)
+		}
+		expected, score := scoreOf(days[i].Spend, spends, method)
+		if math.Abs(score) > threshold && math.Abs(days[i].Spend-expected) >= minDelta {
+			anomalies = append(anomalies, Anomaly{Day: days[i], Expected: expected, Score: score, Window: len(spends)})
+		}
+	}
+	return anomalies
+}
+
+// scoreOf returns the spend expected from the window and the score of the spend against it.
+// When most of the window has the same spend, its median absolute deviation is 0
+// and the mean absolute deviation is used instead, down to a cent when the spend doesn't vary at all.
+func scoreOf(spend float64, window []float64, method string) (float64, float64) {
+	var expected, spread float64
+	if method == ZScore {
+		expected = mean(window)
+		deviations := make([]float64, 0, len(window))
+		for _, value := range window {
+			deviations = append(deviations, (value-expected)*(value-expected))
+		}
+		spread = math.Sqrt(mean(deviations))
+	} else {
+		expected = median(window)
+		deviations := make([]float64, 0, len(window))
+		for _, value := range window {
+			deviations = append(deviations, math.Abs(value-expected))
+		}
+		spread = median(deviations) / madScale
+		if spread == 0 {
+			spread = mean(deviations) / meanADScale
+		}
+	}
+	if spread < minSpread {
+		spread = minSpread
+	}
+	return expected, (spend - expected) / spread
+}
+
+// mean returns the mean of the values.
+func mean(values []float64) float64 {
+	sum := 0.0
+	for _, value := range values {
+		sum += value
+	}
+	return sum / float64(len(values))
+}
+
+// median returns the median of the values.
+func median(values []float64) float64 {
+	sorted := append([]float64{}, values...)
+	sort.Float64s(sorted)
+	middle := len(sorted) / 2
+	if len(sorted)%2 == 0 {
+		return (sorted[middle-1] + sorted[middle]) / 2
+	}
+	return sorted[middle]
+}
@@ -0,0 +1,94 @@
+package anomaly
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+// daysOf returns the days of the spends, one after another.
+func daysOf(spends ...float64) []Day {
+	start := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	days := make([]Day, 0, len(spends))
+	for i, spend := range spends {
+		days = append(days, Day{Date: start.AddDate(0, 0, i), Spend: spend})
+	}
+	return days
+}
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		name     string
+		method   string
+		spends   []float64
+		minDelta float64
+		// anomalous are the indexes of the anomalous days, and above tells whether each is above the expected spend.
+		anomalous []int
+		above     []bool
+	}{
+		{"mad steady", MAD, []float64{10, 11, 9, 10, 12, 10, 11, 9, 10}, 1, nil, nil},
+		{"mad spike", MAD, []float64{10, 11, 9, 10, 12, 10, 11, 9, 10, 45}, 1, []int{9}, []bool{true}},
+		{"mad drop", MAD, []float64{10, 11, 9, 10, 12, 10, 11, 9, 10, 1}, 1, []int{9}, []bool{false}},
+		{"mad spike not skewing", MAD, []float64{10, 11, 9, 10, 45, 10, 11, 9, 10, 44}, 1, []int{4, 9}, []bool{true, true}},
+		{"zscore steady", ZScore, []float64{10, 11, 9, 10, 12, 10, 11, 9, 10}, 1, nil, nil},
+		{"zscore spike", ZScore, []float64{10, 11, 9, 10, 12, 10, 11, 9, 10, 45}, 1, []int{9}, []bool{true}},
+		{"zscore drop", ZScore, []float64{10, 11, 9, 10, 12, 10, 11, 9, 10, 1}, 1, []int{9}, []bool{false}},
+		{"too few days", MAD, []float64{10, 10, 45}, 1, nil, nil},
+		{"mad mostly flat", MAD, []float64{5, 5, 5, 5, 5, 5.5, 5, 5, 5.8}, 1, nil, nil},
+		{"mad mostly flat spike", MAD, []float64{5, 5, 5, 5, 5, 5.5, 5, 5, 20}, 1, []int{8}, []bool{true}},
+		{"mad flat", MAD, []float64{5, 5, 5, 5, 5, 5.5}, 1, nil, nil},
+		{"mad flat spike", MAD, []float64{5, 5, 5, 5, 5, 8}, 1, []int{5}, []bool{true}},
+		{"zscore flat", ZScore, []float64{5, 5, 5, 5, 5, 5.5}, 1, nil, nil},
+		{"zscore flat drop", ZScore, []float64{5, 5, 5, 5, 5, 2}, 1, []int{5}, []bool{false}},
+		{"zscore flat small delta", ZScore, []float64{5, 5, 5, 5, 5, 5.5}, 0.1, []int{5}, []bool{true}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			days := daysOf(test.spends...)
+			anomalies := Detect(days, 14, DefaultThresholds[test.method], test.minDelta, test.method)
+			if len(anomalies) != len(test.anomalous) {
+				t.Fatalf("got %d anomalies %v, want %d", len(anomalies), anomalies, len(test.anomalous))
+			}
+			for i, a := range anomalies {
+				if !a.Day.Date.Equal(days[test.anomalous[i]].Date) {
+					t.Errorf("anomaly %d is on %s, want %s", i, a.Day.Date, days[test.anomalous[i]].Date)
+				}
+				if math.IsInf(a.Score, 0) || math.IsNaN(a.Score) {
+					t.Errorf("anomaly %d scored %f, want a finite score", i, a.Score)
+				}
+				if (a.Score > 0) != test.above[i] || (a.Delta() > 0) != test.above[i] {
+					t.Errorf("anomaly %d scored %f with a delta of %f, want above %t", i, a.Score, a.Delta(), test.above[i])
+				}
+			}
+		})
+	}
+}
+
+func TestScoreOf(t *testing.T) {
+	tests := []struct {
+		name     string
+		method   string
+		spend    float64
+		window   []float64
+		expected float64
+		score    float64
+	}{
+		{"mad", MAD, 14, []float64{8, 9, 10, 11, 12}, 10, 4 * madScale},
+		{"zscore", ZScore, 14, []float64{8, 12}, 10, 2},
+		{"mad mean absolute deviation", MAD, 10, []float64{5, 5, 5, 5, 10}, 5, 5 / (1.0 / meanADScale)},
+		{"mad flat", MAD, 6, []float64{5, 5, 5}, 5, 1 / minSpread},
+		{"zscore flat", ZScore, 4, []float64{5, 5, 5}, 5, -1 / minSpread},
+		{"flat same", MAD, 5, []float64{5, 5, 5}, 5, 0},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			expected, score := scoreOf(test.spend, test.window, test.method)
+			if expected != test.expected {
+				t.Errorf("got expected spend %f, want %f", expected, test.expected)
+			}
+			if math.Abs(score-test.score) > 1e-9 {
+				t.Errorf("got score %f, want %f", score, test.score)
+			}
+		})
+	}
+}
@@ -0,0 +1,223 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/WUMUXIAN/aws-slack-bot/config"
+	"github.com/WUMUXIAN/aws-slack-bot/history"
+	"github.com/WUMUXIAN/aws-slack-bot/jobs"
+	"github.com/WUMUXIAN/aws-slack-bot/notify"
+	"github.com/WUMUXIAN/aws-slack-bot/report"
+	"github.com/WUMUXIAN/aws-slack-bot/rules"
+	"github.com/WUMUXIAN/aws-slack-bot/stats"
+)
+
+// schedule is a report the bot sends on its own cron definition.
+type schedule struct {
+	name           string
+	cronDefinition string
+	location       *time.Location
+	regions        []string
+	collectors     []stats.Collector
+	notifiers      []notify.Notifier
+	options        jobs.Options
+	// alerts is set on the schedule checking the rules to alert on between the reports.
+	alerts bool
+	// anomalies is set on the schedule detecting the anomalous days of the spend.
+	anomalies *jobs.AnomalySettings
+}
+
+// job is a job the bot runs, on schedule or once.
+type job interface {
+	Run()
+	RunOnce() error
+}
+
+// bot is what a validated configuration sets up.
+type bot struct {
+	schedules []schedule
+}
+
+// loadBot reads and validates the configuration, from the file at path if any, and sets up the bot.
+func loadBot(path string) (bot, error) {
+	c, err := config.Load(path)
+	if err != nil {
+		return bot{}, err
+	}
+	if err := c.Validate(); err != nil {
+		return bot{}, err
+	}
+	// The schedules share the history, so that each can compare with what the others reported.
+	var store history.Store
+	var members history.MemberStore
+	if c.HistoryDir != "" {
+		files := history.NewFiles(c.HistoryDir)
+		store, members = files, files
+	}
+
+	accounts := make([]jobs.Account, 0, len(c.Accounts))
+	for _, a := range c.Accounts {
+		accounts = append(accounts, jobs.Account{Name: a.Name(), ID: a.ID, RoleARN: a.RoleARN, ExternalID: a.ExternalID})
+	}
+
+	var organization *jobs.Organization
+	if c.Organization.Discover {
+		organization = &jobs.Organization{
+			RoleName:   c.Organization.RoleName,
+			ExternalID: c.Organization.ExternalID,
+			Filter:     stats.OrganizationFilter{OUs: c.Organization.OUs, Tags: c.Organization.Tags},
+		}
+	}
+
+	// The configuration was validated, so are the collectors it sets up.
+	costExplorer, _ := stats.NewCostExplorerCollector(c.CostExplorer.Cost, c.CostExplorer.GroupBy)
+	tagCosts, _ := stats.NewTagCostsCollector(c.CostExplorer.Cost, c.CostAllocation.Tags, c.CostAllocation.UntaggedWarningPercent)
+	commitments, _ := stats.NewCommitmentsCollector(c.Commitments.ExpiringDays)
+	configured := []stats.Collector{costExplorer, tagCosts, commitments}
+
+	routes := make([]jobs.Route, 0, len(c.CostAllocation.Routes))
+	for _, route := range c.CostAllocation.Routes {
+		routes = append(routes, jobs.Route{
+			Tag:       route.Tag,
+			Value:     route.Value,
+			Notifiers: newNotifiers(c.DestinationsNamed(route.Destinations)),
+		})
+	}
+
+	var b bot
+	schedules := c.ResolvedSchedules()
+	alertChecks, alerts := c.AlertChecks()
+	if alerts {
+		schedules = append(schedules, alertChecks)
+	}
+	anomalyChecks, anomalies := c.AnomalyChecks()
+	if anomalies {
+		schedules = append(schedules, anomalyChecks)
+	}
+	for _, s := range schedules {
+		collectors, err := stats.Select(s.Collectors)
+		if err != nil {
+			return bot{}, err
+		}
+		for i, collector := range collectors {
+			for _, replacement := range configured {
+				if collector.Name() == replacement.Name() {
+					collectors[i] = replacement
+				}
+			}
+		}
+		scheduleRules, scheduleStore, scheduleMembers := newRules(c.Rules, false), store, members
+		if s.Name == config.AlertSchedule {
+			// The checks compare with the thresholds only, the history is for the reports.
+			scheduleRules, scheduleStore = newRules(c.Rules, true), nil
+		}
+		if s.Name == config.AlertSchedule || s.Name == config.AnomalySchedule {
+			// Only the reports tell the accounts that joined or left, the checks would miss them.
+			scheduleMembers = nil
+		}
+		var anomalySettings *jobs.AnomalySettings
+		if s.Name == config.AnomalySchedule {
+			anomalySettings = &jobs.AnomalySettings{
+				Method:    c.Anomalies.Method,
+				Window:    c.Anomalies.Window,
+				Threshold: c.Anomalies.Threshold,
+				MinDelta:  c.Anomalies.MinDelta,
+			}
+		}
+		b.schedules = append(b.schedules, schedule{
+			name:           s.Name,
+			cronDefinition: s.Cron,
+			location:       s.Location(),
+			regions:        s.Regions,
+			collectors:     collectors,
+			notifiers:      newNotifiers(c.DestinationsOf(s)),
+			options: jobs.Options{
+				Name:             s.Name,
+				RunTimeout:       c.RunTimeoutDuration(),
+				CollectorTimeout: c.CollectorTimeoutDuration(),
+				Concurrency:      c.Concurrency,
+				Location:         s.Location(),
+				History:          scheduleStore,
+				ChangeWarning:    c.Changes.WarningPercent,
+				ChangeCritical:   c.Changes.CriticalPercent,
+				Rules:            scheduleRules,
+				Accounts:         accounts,
+				Organization:     organization,
+				Members:          scheduleMembers,
+				Routes:           routes,
+			},
+			alerts:    s.Name == config.AlertSchedule,
+			anomalies: anomalySettings,
+		})
+	}
+	return b, nil
+}
+
+// requireDestinations returns an error if a report has nowhere to go.
+func (b bot) requireDestinations() error {
+	for _, s := range b.schedules {
+		if len(s.notifiers) == 0 {
+			return fmt.Errorf("please specify the slack webhook URL or another destination")
+		}
+	}
+	return nil
+}
+
+// selectSchedules returns the schedule of given name, or all of them if name is empty.
+func (b bot) selectSchedules(name string) ([]schedule, error) {
+	if name == "" {
+		return b.schedules, nil
+	}
+	for _, s := range b.schedules {
+		if s.name == name {
+			return []schedule{s}, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown schedule %q", name)
+}
+
+// newSlackJob creates the slack job of the schedule.
+func (s schedule) newSlackJob() jobs.SlackJob {
+	return jobs.NewSlackJob(s.regions, s.collectors, s.notifiers, s.options)
+}
+
+// newJob creates the job of the schedule, sending either a report, the new alerts or the new anomalies.
+func (s schedule) newJob() job {
+	if s.anomalies != nil {
+		return jobs.NewAnomalyJob(s.newSlackJob(), *s.anomalies)
+	}
+	if s.alerts {
+		return jobs.NewAlertJob(s.newSlackJob())
+	}
+	return s.newSlackJob()
+}
+
+// newRules converts the configured rules, only those to alert on if alertsOnly is set.
+func newRules(configured []config.Rule, alertsOnly bool) []rules.Rule {
+	converted := make([]rules.Rule, 0, len(configured))
+	for _, rule := range configured {
+		if alertsOnly && !rule.Alert {
+			continue
+		}
+		r := rules.Rule{
+			Name:     rule.Name,
+			Service:  rule.Service,
+			Metric:   rule.Metric,
+			Account:  rule.Account,
+			Region:   rule.Region,
+			Label:    rule.Label,
+			Severity: report.Severity(rule.Severity),
+		}
+		if r.Severity == report.Normal {
+			r.Severity = report.Warning
+		}
+		if rule.Above != nil {
+			r.Condition, r.Threshold = rules.Above, *rule.Above
+		} else {
+			r.Condition, r.Threshold = rules.Below, *rule.Below
+		}
+		converted = append(converted, r)
+	}
+	return converted
+}
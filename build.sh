@@ -3,10 +3,14 @@
 # Build the app for linux first
 GOOS=linux GOARCH=amd64 CGO_ENABLED=0 go build -a -tags netgo -ldflags '-w' .
 
+# Bundle the timezone database of Go for the scheduling timezones
+cp "$(go env GOROOT)/lib/time/zoneinfo.zip" .
+
 # Build the image
 docker build -t wumuxian/aws-slack-bot:latest .
 
 # Clear up
+rm -f zoneinfo.zip
 images=$(docker images -q --filter "dangling=true")
 echo $images
 if [ "$images" != "" ]; then
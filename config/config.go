@@ -0,0 +1,425 @@
+// Package config reads the configuration of the bot from a YAML or JSON file,
+// overridden by environment variables.
+package config
+
+import (
+	"fmt"
+	"io/ioutil"
+	"time"
+
+	"github.com/WUMUXIAN/aws-slack-bot/anomaly"
+	"github.com/WUMUXIAN/aws-slack-bot/stats"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// Destination types.
+const (
+	Slack   = "slack"
+	Teams   = "teams"
+	Discord = "discord"
+	Webhook = "webhook"
+	Email   = "email"
+	File    = "file"
+)
+
+// DefaultSchedule is the name of the schedule used when none is configured.
+const DefaultSchedule = "default"
+
+// AlertSchedule is the name of the schedule checking the rules to alert on.
+const AlertSchedule = "alerts"
+
+// AnomalySchedule is the name of the schedule detecting the anomalous days of the spend.
+const AnomalySchedule = "anomalies"
+
+// Config is the configuration of the bot.
+// Durations are kept as written, e.g. "90s" or "5m", so that Validate can report them.
+// The regions, schedule, timezone and collectors are the defaults of the schedules.
+type Config struct {
+	Regions          []string      `yaml:"regions"`
+	Schedule         string        `yaml:"schedule"`
+	Timezone         string        `yaml:"timezone"`
+	Collectors       []string      `yaml:"collectors"`
+	Schedules        []Schedule    `yaml:"schedules"`
+	Destinations     []Destination `yaml:"destinations"`
+	RunTimeout       string        `yaml:"run_timeout"`
+	CollectorTimeout string        `yaml:"collector_timeout"`
+	Concurrency      int           `yaml:"concurrency"`
+	// HistoryDir is where the metrics of every report are kept, if set.
+	HistoryDir string    `yaml:"history_dir"`
+	Changes    Changes   `yaml:"changes"`
+	Rules      []Rule    `yaml:"rules"`
+	Alerts     Alerts    `yaml:"alerts"`
+	Anomalies  Anomalies `yaml:"anomalies"`
+	// CostExplorer sets what the costexplorer collector reports.
+	CostExplorer   CostExplorer   `yaml:"cost_explorer"`
+	CostAllocation CostAllocation `yaml:"cost_allocation"`
+	Commitments    Commitments    `yaml:"commitments"`
+	// Accounts are watched instead of the account of the credentials, if any.
+	Accounts     []Account    `yaml:"accounts"`
+	Organization Organization `yaml:"organization"`
+}
+
+// Organization discovers the accounts to watch among the members of the organization,
+// from its management account, those in any of the OUs and with all the tags if set.
+// The role is assumed in the member accounts, the credentials being used as they are in the management account.
+type Organization struct {
+	Discover   bool              `yaml:"discover"`
+	RoleName   string            `yaml:"role_name"`
+	ExternalID string            `yaml:"external_id"`
+	OUs        []string          `yaml:"ous"`
+	Tags       map[string]string `yaml:"tags"`
+}
+
+// Account is an AWS account the bot watches by assuming a role in it,
+// or with the credentials as they are if no role is set.
+// The alias tells the account apart in the reports, its ID if it has none.
+type Account struct {
+	ID         string `yaml:"id"`
+	RoleARN    string `yaml:"role_arn"`
+	ExternalID string `yaml:"external_id"`
+	Alias      string `yaml:"alias"`
+}
+
+// Name returns the alias of the account, or its ID if it has none.
+func (a Account) Name() string {
+	if a.Alias != "" {
+		return a.Alias
+	}
+	return a.ID
+}
+
+// Rule marks the metrics of a service whose value is above or below a threshold, e.g. "RDS CPU above 80%".
+// The account (its alias or ID), region and label select any metric when empty.
+type Rule struct {
+	Name     string   `yaml:"name"`
+	Service  string   `yaml:"service"`
+	Metric   string   `yaml:"metric"`
+	Account  string   `yaml:"account"`
+	Region   string   `yaml:"region"`
+	Label    string   `yaml:"label"`
+	Above    *float64 `yaml:"above"`
+	Below    *float64 `yaml:"below"`
+	Severity string   `yaml:"severity"`
+	// Alert sends the broken rule right away, rather than waiting for the next report.
+	Alert bool `yaml:"alert"`
+}
+
+// Alerts sets when the rules to alert on are checked between the reports, and who is alerted,
+// all destinations if none is named.
+type Alerts struct {
+	Schedule     string   `yaml:"schedule"`
+	Destinations []string `yaml:"destinations"`
+}
+
+// Anomalies sets when the spend of the last complete day is checked against the days before it, if ever,
+// how, and who is alerted, all destinations if none is named.
+// The method is "mad" or "zscore", the threshold the score above which a day is anomalous, the method's default if 0,
+// and the minimum delta the difference in USD with the expected spend below which a day isn't anomalous.
+type Anomalies struct {
+	Schedule     string   `yaml:"schedule"`
+	Method       string   `yaml:"method"`
+	Window       int      `yaml:"window"`
+	Threshold    float64  `yaml:"threshold"`
+	MinDelta     float64  `yaml:"min_delta"`
+	Destinations []string `yaml:"destinations"`
+}
+
+// CostExplorer sets the cost the costexplorer collector reports, "unblended" or "amortized",
+// and what it is grouped by besides the total, any of "day", "service", "linked_account" and "usage_type".
+type CostExplorer struct {
+	Cost    string   `yaml:"cost"`
+	GroupBy []string `yaml:"group_by"`
+}
+
+// CostAllocation sets the cost allocation tag keys the tags collector groups the cost by,
+// the share of the total in percent above which the untagged cost is marked, 0 meaning never,
+// and the routes sending the cost of a tag value to destinations of its own.
+type CostAllocation struct {
+	Tags                   []string `yaml:"tags"`
+	UntaggedWarningPercent float64  `yaml:"untagged_warning_percent"`
+	Routes                 []Route  `yaml:"routes"`
+}
+
+// Route sends the cost of a tag value, e.g. of a team, to destinations of its own,
+// which only get the reports of the schedules that name them.
+type Route struct {
+	Tag          string   `yaml:"tag"`
+	Value        string   `yaml:"value"`
+	Destinations []string `yaml:"destinations"`
+}
+
+// Commitments sets how many days before they expire the commitments collector lists the reservations.
+type Commitments struct {
+	ExpiringDays int `yaml:"expiring_days"`
+}
+
+// Changes sets the changes in percent since an earlier report above which a metric is marked, 0 meaning never.
+type Changes struct {
+	WarningPercent  float64 `yaml:"warning_percent"`
+	CriticalPercent float64 `yaml:"critical_percent"`
+}
+
+// Schedule is a report sent on its own cron definition.
+// Whatever isn't set is taken from the configuration, and all destinations are used if none is named.
+type Schedule struct {
+	Name         string   `yaml:"name"`
+	Cron         string   `yaml:"cron"`
+	Timezone     string   `yaml:"timezone"`
+	Regions      []string `yaml:"regions"`
+	Collectors   []string `yaml:"collectors"`
+	Destinations []string `yaml:"destinations"`
+}
+
+// Destination is where a report is sent to.
+// Which of the fields are used depends on the type.
+type Destination struct {
+	Name   string `yaml:"name"`
+	Type   string `yaml:"type"`
+	Format string `yaml:"format"`
+	// URL of the slack, teams, discord or webhook destinations.
+	URL string `yaml:"url"`
+	// SMTP server and addresses of the email destinations.
+	Addr     string   `yaml:"addr"`
+	Username string   `yaml:"username"`
+	Password string   `yaml:"password"`
+	From     string   `yaml:"from"`
+	To       []string `yaml:"to"`
+	// Path of the file destinations.
+	Path string `yaml:"path"`
+}
+
+// Default returns the configuration used for whatever isn't set.
+func Default() Config {
+	return Config{
+		Regions:          []string{"us-east-1"},
+		Schedule:         "0 0 1 * * MON-FRI",
+		Timezone:         "UTC",
+		Collectors:       []string{},
+		Destinations:     []Destination{},
+		RunTimeout:       "10m",
+		CollectorTimeout: "2m",
+		Concurrency:      8,
+		Changes: Changes{
+			WarningPercent:  20,
+			CriticalPercent: 50,
+		},
+		Rules:    []Rule{},
+		Accounts: []Account{},
+		Organization: Organization{
+			RoleName: "OrganizationAccountAccessRole",
+		},
+		Alerts: Alerts{
+			Schedule: "0 */15 * * * *",
+		},
+		Anomalies: Anomalies{
+			Method:   anomaly.MAD,
+			Window:   14,
+			MinDelta: anomaly.DefaultMinDelta,
+		},
+		CostExplorer: CostExplorer{
+			Cost:    stats.UnblendedCost,
+			GroupBy: []string{stats.ByService},
+		},
+		CostAllocation: CostAllocation{
+			Tags:                   []string{},
+			UntaggedWarningPercent: 10,
+			Routes:                 []Route{},
+		},
+		Commitments: Commitments{
+			ExpiringDays: 30,
+		},
+	}
+}
+
+// Load reads the configuration file at path, if any, and applies the environment variables over it.
+// Both YAML and JSON files are accepted, unknown keys are an error.
+func Load(path string) (Config, error) {
+	c := Default()
+	if path != "" {
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return c, fmt.Errorf("failed to read config file: %s", err.Error())
+		}
+		// YAML being a superset of JSON, a single decoder reads both.
+		if err := yaml.UnmarshalStrict(data, &c); err != nil {
+			return c, fmt.Errorf("failed to parse config file %s: %s", path, err.Error())
+		}
+	}
+	c.applyEnv()
+	c.nameDestinations()
+	return c, nil
+}
+
+// nameDestinations names the unnamed destinations after their type, numbered if there is more than one.
+func (c *Config) nameDestinations() {
+	count := make(map[string]int)
+	for _, d := range c.Destinations {
+		if d.Name == "" {
+			count[d.Type]++
+		}
+	}
+	seen := make(map[string]int)
+	for i, d := range c.Destinations {
+		if d.Name != "" {
+			continue
+		}
+		seen[d.Type]++
+		c.Destinations[i].Name = d.Type
+		if count[d.Type] > 1 {
+			c.Destinations[i].Name = fmt.Sprintf("%s #%d", d.Type, seen[d.Type])
+		}
+	}
+}
+
+// ResolvedSchedules returns the schedules with the defaults of the configuration filled in,
+// or a single schedule made of the defaults if none is configured.
+func (c Config) ResolvedSchedules() []Schedule {
+	schedules := c.Schedules
+	if len(schedules) == 0 {
+		schedules = []Schedule{{Name: DefaultSchedule}}
+	}
+	resolved := make([]Schedule, 0, len(schedules))
+	for _, s := range schedules {
+		if s.Cron == "" {
+			s.Cron = c.Schedule
+		}
+		if s.Timezone == "" {
+			s.Timezone = c.Timezone
+		}
+		if len(s.Regions) == 0 {
+			s.Regions = c.Regions
+		}
+		if len(s.Collectors) == 0 {
+			s.Collectors = c.Collectors
+		}
+		if len(s.Destinations) == 0 {
+			s.Destinations = c.destinationNames()
+		}
+		resolved = append(resolved, s)
+	}
+	return resolved
+}
+
+// Location returns the timezone of a resolved and validated schedule.
+func (s Schedule) Location() *time.Location {
+	location, err := time.LoadLocation(s.Timezone)
+	if err != nil {
+		return time.UTC
+	}
+	return location
+}
+
+// AlertChecks returns the schedule checking the rules to alert on, if any, made of the defaults,
+// the alerts' settings and the services of the rules.
+func (c Config) AlertChecks() (Schedule, bool) {
+	collectors := make([]string, 0)
+	seen := make(map[string]bool)
+	for _, rule := range c.Rules {
+		if rule.Alert && !seen[rule.Service] {
+			seen[rule.Service] = true
+			collectors = append(collectors, rule.Service)
+		}
+	}
+	if len(collectors) == 0 {
+		return Schedule{}, false
+	}
+	s := Schedule{
+		Name:         AlertSchedule,
+		Cron:         c.Alerts.Schedule,
+		Timezone:     c.Timezone,
+		Regions:      c.Regions,
+		Collectors:   collectors,
+		Destinations: c.Alerts.Destinations,
+	}
+	if len(s.Destinations) == 0 {
+		s.Destinations = c.destinationNames()
+	}
+	return s, true
+}
+
+// AnomalyChecks returns the schedule detecting the anomalous days of the spend, if any, made of the defaults
+// and the anomalies' settings.
+func (c Config) AnomalyChecks() (Schedule, bool) {
+	if c.Anomalies.Schedule == "" {
+		return Schedule{}, false
+	}
+	s := Schedule{
+		Name:         AnomalySchedule,
+		Cron:         c.Anomalies.Schedule,
+		Timezone:     c.Timezone,
+		Regions:      c.Regions,
+		Collectors:   []string{"billing"},
+		Destinations: c.Anomalies.Destinations,
+	}
+	if len(s.Destinations) == 0 {
+		s.Destinations = c.destinationNames()
+	}
+	return s, true
+}
+
+// destinationNames returns the names of all destinations but those of the routes.
+func (c Config) destinationNames() []string {
+	routed := make(map[string]bool)
+	for _, route := range c.CostAllocation.Routes {
+		for _, name := range route.Destinations {
+			routed[name] = true
+		}
+	}
+	names := make([]string, 0, len(c.Destinations))
+	for _, d := range c.Destinations {
+		if !routed[d.Name] {
+			names = append(names, d.Name)
+		}
+	}
+	return names
+}
+
+// DestinationsOf returns the destinations a schedule sends its report to.
+func (c Config) DestinationsOf(s Schedule) []Destination {
+	return c.DestinationsNamed(s.Destinations)
+}
+
+// DestinationsNamed returns the destinations of given names.
+func (c Config) DestinationsNamed(names []string) []Destination {
+	destinations := make([]Destination, 0, len(names))
+	for _, name := range names {
+		for _, d := range c.Destinations {
+			if d.Name == name {
+				destinations = append(destinations, d)
+			}
+		}
+	}
+	return destinations
+}
+
+// FormatOf returns the format a destination renders reports in, defaulting by its type.
+func (d Destination) FormatOf() string {
+	if d.Format != "" {
+		return d.Format
+	}
+	switch d.Type {
+	case Slack:
+		return "blocks"
+	case Teams:
+		return "teams"
+	case Discord:
+		return "discord"
+	case Email:
+		return "html"
+	case File:
+		return fileFormat(d.Path)
+	}
+	return "json"
+}
+
+// RunTimeoutDuration returns the run timeout of a validated configuration.
+func (c Config) RunTimeoutDuration() time.Duration {
+	d, _ := time.ParseDuration(c.RunTimeout)
+	return d
+}
+
+// CollectorTimeoutDuration returns the collector timeout of a validated configuration.
+func (c Config) CollectorTimeoutDuration() time.Duration {
+	d, _ := time.ParseDuration(c.CollectorTimeout)
+	return d
+}
@@ -0,0 +1,136 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// SplitEnv splits a comma separated environment variable, ignoring empty items.
+func SplitEnv(key string) []string {
+	items := make([]string, 0)
+	for _, item := range strings.Split(os.Getenv(key), ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// applyEnv overrides the configuration with the environment variables that are set.
+// A variable setting destinations replaces all the destinations of its type.
+func (c *Config) applyEnv() {
+	if regions := SplitEnv("REGIONS"); len(regions) > 0 {
+		c.Regions = regions
+	}
+	if os.Getenv("CRON_DEFINITION") != "" {
+		c.Schedule = os.Getenv("CRON_DEFINITION")
+	}
+	if os.Getenv("TIMEZONE") != "" {
+		c.Timezone = os.Getenv("TIMEZONE")
+	}
+	if collectors := SplitEnv("COLLECTORS"); len(collectors) > 0 {
+		c.Collectors = collectors
+	}
+	if os.Getenv("RUN_TIMEOUT") != "" {
+		c.RunTimeout = os.Getenv("RUN_TIMEOUT")
+	}
+	if os.Getenv("COLLECTOR_TIMEOUT") != "" {
+		c.CollectorTimeout = os.Getenv("COLLECTOR_TIMEOUT")
+	}
+	if os.Getenv("ANOMALY_SCHEDULE") != "" {
+		c.Anomalies.Schedule = os.Getenv("ANOMALY_SCHEDULE")
+	}
+	if os.Getenv("HISTORY_DIR") != "" {
+		c.HistoryDir = os.Getenv("HISTORY_DIR")
+	}
+	c.Changes.WarningPercent = envPercent("CHANGE_WARNING_PERCENT", c.Changes.WarningPercent)
+	c.Changes.CriticalPercent = envPercent("CHANGE_CRITICAL_PERCENT", c.Changes.CriticalPercent)
+	if os.Getenv("CONCURRENCY") != "" {
+		// An invalid number is kept negative for Validate to report.
+		concurrency, err := strconv.Atoi(os.Getenv("CONCURRENCY"))
+		if err != nil {
+			concurrency = -1
+		}
+		c.Concurrency = concurrency
+	}
+
+	// Webhooks
+	c.overrideWebhooks(Slack, "SLACK_WEBHOOK_URL", os.Getenv("SLACK_FORMAT"))
+	c.overrideWebhooks(Teams, "TEAMS_WEBHOOK_URL", "")
+	c.overrideWebhooks(Discord, "DISCORD_WEBHOOK_URL", "")
+	c.overrideWebhooks(Webhook, "WEBHOOK_URL", "")
+
+	// Email
+	if os.Getenv("SMTP_ADDR") != "" {
+		c.replace(Email, Destination{
+			Type:     Email,
+			Addr:     os.Getenv("SMTP_ADDR"),
+			Username: os.Getenv("SMTP_USERNAME"),
+			Password: os.Getenv("SMTP_PASSWORD"),
+			From:     os.Getenv("SMTP_FROM"),
+			To:       SplitEnv("SMTP_TO"),
+		})
+	}
+
+	// File
+	if os.Getenv("REPORT_FILE") != "" {
+		c.replace(File, Destination{Type: File, Path: os.Getenv("REPORT_FILE")})
+	}
+}
+
+// envPercent reads a percentage from an environment variable, falling back to def when it isn't set.
+// An invalid number is kept negative for Validate to report.
+func envPercent(key string, def float64) float64 {
+	if os.Getenv(key) == "" {
+		return def
+	}
+	percent, err := strconv.ParseFloat(os.Getenv(key), 64)
+	if err != nil {
+		return -1
+	}
+	return percent
+}
+
+// overrideWebhooks replaces the destinations of given type with the URLs of key, if set,
+// and sets their format, if given.
+func (c *Config) overrideWebhooks(destinationType, key, format string) {
+	if urls := SplitEnv(key); len(urls) > 0 {
+		destinations := make([]Destination, 0, len(urls))
+		for _, url := range urls {
+			destinations = append(destinations, Destination{Type: destinationType, URL: url})
+		}
+		c.replace(destinationType, destinations...)
+	}
+	if format == "" {
+		return
+	}
+	for i := range c.Destinations {
+		if c.Destinations[i].Type == destinationType {
+			c.Destinations[i].Format = format
+		}
+	}
+}
+
+// replace replaces the destinations of given type.
+func (c *Config) replace(destinationType string, destinations ...Destination) {
+	kept := make([]Destination, 0, len(c.Destinations)+len(destinations))
+	for _, d := range c.Destinations {
+		if d.Type != destinationType {
+			kept = append(kept, d)
+		}
+	}
+	c.Destinations = append(kept, destinations...)
+}
+
+// fileFormat returns the format matching the extension of a report file.
+func fileFormat(path string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		return "json"
+	case ".html", ".htm":
+		return "html"
+	}
+	return "markdown"
+}
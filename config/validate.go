@@ -0,0 +1,369 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/WUMUXIAN/aws-slack-bot/anomaly"
+	"github.com/WUMUXIAN/aws-slack-bot/render"
+	"github.com/WUMUXIAN/aws-slack-bot/report"
+	"github.com/WUMUXIAN/aws-slack-bot/stats"
+	"github.com/robfig/cron"
+)
+
+// Problems lists everything wrong with a configuration.
+type Problems []string
+
+// Error implements error.
+func (o Problems) Error() string {
+	return "invalid configuration:\n  - " + strings.Join(o, "\n  - ")
+}
+
+// add records a problem.
+func (o *Problems) add(format string, args ...interface{}) {
+	*o = append(*o, fmt.Sprintf(format, args...))
+}
+
+// Validate checks the whole configuration and returns the Problems found, if any.
+func (c Config) Validate() error {
+	var problems Problems
+
+	if len(c.Regions) == 0 {
+		problems.add("regions: at least one region is required")
+	}
+	validateRegions(&problems, "regions", c.Regions)
+	validateCron(&problems, "schedule", c.Schedule)
+	validateTimezone(&problems, "timezone", c.Timezone)
+	validateCollectors(&problems, "collectors", c.Collectors)
+
+	validateDuration(&problems, "run_timeout", c.RunTimeout)
+	validateDuration(&problems, "collector_timeout", c.CollectorTimeout)
+	if c.Concurrency < 0 {
+		problems.add("concurrency: must be a non-negative number")
+	}
+	if c.Changes.WarningPercent < 0 {
+		problems.add("changes.warning_percent: must be a non-negative number")
+	}
+	if c.Changes.CriticalPercent < 0 {
+		problems.add("changes.critical_percent: must be a non-negative number")
+	}
+	if c.Changes.WarningPercent > 0 && c.Changes.CriticalPercent > 0 && c.Changes.CriticalPercent < c.Changes.WarningPercent {
+		problems.add("changes.critical_percent: must not be below the warning_percent")
+	}
+
+	names := make(map[string]bool)
+	for i, d := range c.Destinations {
+		key := fmt.Sprintf("destinations[%d]", i)
+		if names[d.Name] {
+			problems.add("%s: duplicate destination name %q", key, d.Name)
+		}
+		names[d.Name] = true
+		d.validate(&problems, key)
+	}
+
+	schedules := make(map[string]bool)
+	for i, s := range c.Schedules {
+		key := fmt.Sprintf("schedules[%d]", i)
+		if s.Name == "" {
+			problems.add("%s: the schedule name is missing", key)
+		} else if schedules[s.Name] {
+			problems.add("%s: duplicate schedule name %q", key, s.Name)
+		} else if s.Name == AlertSchedule {
+			problems.add("%s: the schedule name %q is kept for the alert checks", key, s.Name)
+		} else if s.Name == AnomalySchedule {
+			problems.add("%s: the schedule name %q is kept for the anomaly checks", key, s.Name)
+		}
+		schedules[s.Name] = true
+		if s.Cron != "" {
+			validateCron(&problems, key+".cron", s.Cron)
+		}
+		if s.Timezone != "" {
+			validateTimezone(&problems, key+".timezone", s.Timezone)
+		}
+		validateRegions(&problems, key+".regions", s.Regions)
+		validateCollectors(&problems, key+".collectors", s.Collectors)
+		for _, name := range s.Destinations {
+			if !names[name] {
+				problems.add("%s.destinations: unknown destination %q", key, name)
+			}
+		}
+	}
+
+	validateCron(&problems, "alerts.schedule", c.Alerts.Schedule)
+	for _, name := range c.Alerts.Destinations {
+		if !names[name] {
+			problems.add("alerts.destinations: unknown destination %q", name)
+		}
+	}
+	if c.Anomalies.Schedule != "" {
+		validateCron(&problems, "anomalies.schedule", c.Anomalies.Schedule)
+	}
+	if c.Anomalies.Method != anomaly.MAD && c.Anomalies.Method != anomaly.ZScore {
+		problems.add("anomalies.method: unknown method %q, available methods are %s, %s", c.Anomalies.Method, anomaly.MAD, anomaly.ZScore)
+	}
+	if c.Anomalies.Window < 3 || c.Anomalies.Window > 56 {
+		problems.add("anomalies.window: must be between 3 and 56 days")
+	}
+	if c.Anomalies.Threshold < 0 {
+		problems.add("anomalies.threshold: must be a non-negative number")
+	}
+	if c.Anomalies.MinDelta < 0 {
+		problems.add("anomalies.min_delta: must be a non-negative number")
+	}
+	for _, name := range c.Anomalies.Destinations {
+		if !names[name] {
+			problems.add("anomalies.destinations: unknown destination %q", name)
+		}
+	}
+	if _, err := stats.NewCostExplorerCollector(c.CostExplorer.Cost, c.CostExplorer.GroupBy); err != nil {
+		problems.add("cost_explorer: %s", err.Error())
+	}
+	c.CostAllocation.validate(&problems, names)
+	if _, err := stats.NewCommitmentsCollector(c.Commitments.ExpiringDays); err != nil {
+		problems.add("commitments.expiring_days: %s", err.Error())
+	}
+	if len(c.CostAllocation.Tags) == 0 && c.enables("tags") {
+		problems.add("cost_allocation.tags: at least one tag key is required by the tags collector")
+	}
+	if len(c.CostAllocation.Routes) > 0 && !c.enables("tags") {
+		problems.add("cost_allocation.routes: the routes slice the costs by tag, but no schedule enables the tags collector")
+	}
+	accounts := make(map[string]bool)
+	for i, a := range c.Accounts {
+		key := fmt.Sprintf("accounts[%d]", i)
+		if accounts[a.Name()] {
+			problems.add("%s: duplicate account %q", key, a.Name())
+		}
+		accounts[a.Name()] = true
+		a.validate(&problems, key)
+	}
+	if c.Organization.Discover {
+		c.Organization.validate(&problems)
+	}
+	for i, rule := range c.Rules {
+		key := fmt.Sprintf("rules[%d]", i)
+		rule.validate(&problems, key)
+		// The accounts of an organization are only known once discovered.
+		if rule.Account != "" && !accounts[rule.Account] && !c.Organization.Discover {
+			problems.add("%s.account: unknown account %q", key, rule.Account)
+		}
+	}
+
+	if len(problems) > 0 {
+		return problems
+	}
+	return nil
+}
+
+// validate checks a destination, reporting its problems under key.
+func (d Destination) validate(problems *Problems, key string) {
+	switch d.Type {
+	case Slack, Teams, Discord, Webhook:
+		if d.URL == "" {
+			problems.add("%s: the %s web-hook URL is missing", key, d.Type)
+		} else if u, err := url.Parse(d.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			// The URL isn't printed, web-hook URLs are secrets.
+			problems.add("%s: the %s web-hook URL isn't a valid http(s) URL", key, d.Type)
+		}
+	case Email:
+		if d.Addr == "" || d.From == "" || len(d.To) == 0 {
+			problems.add("%s: addr, from and to are required to send reports by email", key)
+		}
+	case File:
+		if d.Path == "" {
+			problems.add("%s: the path of the report file is missing", key)
+		}
+	default:
+		problems.add("%s: unknown destination type %q, available types are %s", key, d.Type,
+			strings.Join([]string{Slack, Teams, Discord, Webhook, Email, File}, ", "))
+		return
+	}
+
+	format := d.FormatOf()
+	if _, err := render.New(format); err != nil {
+		problems.add("%s: unknown format %q, available formats are %s", key, format, strings.Join(render.Names(), ", "))
+		return
+	}
+	allowed := map[string][]string{
+		Slack:   {"blocks", "attachments"},
+		Teams:   {"teams"},
+		Discord: {"discord"},
+	}[d.Type]
+	if len(allowed) == 0 {
+		return
+	}
+	for _, name := range allowed {
+		if format == name {
+			return
+		}
+	}
+	problems.add("%s: %s destinations only accept the %s format", key, d.Type, strings.Join(allowed, " or "))
+}
+
+// accountID matches an AWS account ID.
+var accountID = regexp.MustCompile(`^[0-9]{12}$`)
+
+// roleARN matches the ARN of an IAM role, capturing its account ID.
+var roleARN = regexp.MustCompile(`^arn:aws[a-z-]*:iam::([0-9]{12}):role/.+$`)
+
+// validate checks an account, reporting its problems under key.
+func (a Account) validate(problems *Problems, key string) {
+	if !accountID.MatchString(a.ID) {
+		problems.add("%s.id: the account ID must be 12 digits", key)
+	}
+	if a.RoleARN == "" {
+		if a.ExternalID != "" {
+			problems.add("%s.external_id: only used to assume the role_arn, which is missing", key)
+		}
+		return
+	}
+	match := roleARN.FindStringSubmatch(a.RoleARN)
+	if match == nil {
+		problems.add("%s.role_arn: invalid role ARN %q, e.g. arn:aws:iam::123456789012:role/aws-slack-bot", key, a.RoleARN)
+	} else if match[1] != a.ID {
+		problems.add("%s.role_arn: the role belongs to account %s, not %s", key, match[1], a.ID)
+	}
+}
+
+// organizationalUnit matches the ID of an organizational unit or of the root of an organization.
+var organizationalUnit = regexp.MustCompile(`^(ou-[0-9a-z]{4,32}-[0-9a-z]{8,32}|r-[0-9a-z]{4,32})$`)
+
+// validate checks the discovery of the accounts of an organization.
+func (o Organization) validate(problems *Problems) {
+	if o.RoleName == "" {
+		problems.add("organization.role_name: the role assumed in the member accounts is missing")
+	}
+	for _, ou := range o.OUs {
+		if !organizationalUnit.MatchString(ou) {
+			problems.add("organization.ous: invalid organizational unit %q, e.g. ou-ab12-cd34ef56", ou)
+		}
+	}
+	for key := range o.Tags {
+		if key == "" {
+			problems.add("organization.tags: a tag key is empty")
+		}
+	}
+}
+
+// validate checks the cost allocation tags and their routes to the destinations of given names.
+func (o CostAllocation) validate(problems *Problems, destinations map[string]bool) {
+	tags := make(map[string]bool)
+	for _, tag := range o.Tags {
+		if tag == "" {
+			problems.add("cost_allocation.tags: a tag key is empty")
+		} else if tags[tag] {
+			problems.add("cost_allocation.tags: duplicate tag key %q", tag)
+		}
+		tags[tag] = true
+	}
+	if o.UntaggedWarningPercent < 0 {
+		problems.add("cost_allocation.untagged_warning_percent: must be a non-negative number")
+	}
+	for i, route := range o.Routes {
+		key := fmt.Sprintf("cost_allocation.routes[%d]", i)
+		if !tags[route.Tag] {
+			problems.add("%s.tag: unknown tag key %q, the routed tag must be one of the tags", key, route.Tag)
+		}
+		if route.Value == "" {
+			problems.add("%s.value: the tag value is missing", key)
+		}
+		if len(route.Destinations) == 0 {
+			problems.add("%s.destinations: at least one destination is required", key)
+		}
+		for _, name := range route.Destinations {
+			if !destinations[name] {
+				problems.add("%s.destinations: unknown destination %q", key, name)
+			}
+		}
+	}
+}
+
+// enables tells whether any schedule enables the collector of given name.
+func (c Config) enables(name string) bool {
+	for _, s := range c.ResolvedSchedules() {
+		for _, collector := range s.Collectors {
+			if collector == name {
+				return true
+			}
+		}
+	}
+	for _, rule := range c.Rules {
+		if rule.Service == name {
+			return true
+		}
+	}
+	return false
+}
+
+// validate checks a rule, reporting its problems under key.
+func (o Rule) validate(problems *Problems, key string) {
+	if o.Name == "" {
+		problems.add("%s: the rule name is missing", key)
+	}
+	if _, ok := stats.Lookup(o.Service); !ok {
+		problems.add("%s.service: unknown collector %q, available collectors are %s", key, o.Service, strings.Join(stats.Names(), ", "))
+	}
+	if o.Metric == "" {
+		problems.add("%s.metric: the metric name is missing, e.g. CPU", key)
+	}
+	if o.Region != "" {
+		validateRegions(problems, key+".region", []string{o.Region})
+	}
+	if (o.Above == nil) == (o.Below == nil) {
+		problems.add("%s: exactly one of above and below is required", key)
+	}
+	if o.Severity != "" && o.Severity != string(report.Warning) && o.Severity != string(report.Critical) {
+		problems.add("%s.severity: must be %s or %s", key, report.Warning, report.Critical)
+	}
+}
+
+// regionName matches the name of a region, e.g. us-east-1, us-gov-west-1 or us-isob-east-1,
+// rather than looking it up in the SDK, which doesn't know the regions opened after its release.
+var regionName = regexp.MustCompile(`^[a-z]{2}(-gov|-iso[a-z]?)?-[a-z]+-[0-9]+$`)
+
+// validateRegions checks the regions are well formed.
+func validateRegions(problems *Problems, key string, regions []string) {
+	for _, region := range regions {
+		if !regionName.MatchString(region) {
+			problems.add("%s: invalid region %q, e.g. us-east-1", key, region)
+		}
+	}
+}
+
+// validateCron checks a cron definition.
+func validateCron(problems *Problems, key, definition string) {
+	if _, err := cron.Parse(definition); err != nil {
+		problems.add("%s: invalid cron definition %q: %s", key, definition, err.Error())
+	}
+}
+
+// validateTimezone checks a timezone is known, e.g. "Asia/Singapore".
+func validateTimezone(problems *Problems, key, timezone string) {
+	if timezone == "" {
+		problems.add("%s: the timezone is missing", key)
+	} else if _, err := time.LoadLocation(timezone); err != nil {
+		problems.add("%s: unknown timezone %q, e.g. UTC or Asia/Singapore", key, timezone)
+	}
+}
+
+// validateCollectors checks the collectors are registered.
+func validateCollectors(problems *Problems, key string, collectors []string) {
+	for _, name := range collectors {
+		if _, ok := stats.Lookup(name); !ok {
+			problems.add("%s: unknown collector %q, available collectors are %s", key, name, strings.Join(stats.Names(), ", "))
+		}
+	}
+}
+
+// validateDuration checks a duration such as "90s" or "5m".
+func validateDuration(problems *Problems, key, value string) {
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		problems.add("%s: invalid duration %q, e.g. 90s or 5m", key, value)
+	} else if d < 0 {
+		problems.add("%s: must not be negative", key)
+	}
+}
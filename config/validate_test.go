@@ -0,0 +1,157 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// validConfig returns the default configuration sending to a slack web-hook.
+func validConfig() Config {
+	c := Default()
+	c.Destinations = []Destination{{Name: "slack", Type: Slack, URL: "https://hooks.slack.com/services/T0/B0/X"}}
+	return c
+}
+
+// problemsOf returns the problems Validate found, none if the configuration is valid.
+func problemsOf(t *testing.T, c Config) Problems {
+	err := c.Validate()
+	if err == nil {
+		return nil
+	}
+	problems, ok := err.(Problems)
+	if !ok {
+		t.Fatalf("got error %v, want Problems", err)
+	}
+	return problems
+}
+
+// route routes the costs of team a by the tag to a destination of their own, the team tag being the one allocated.
+func route(c *Config, tag string) {
+	c.Destinations = append(c.Destinations, Destination{Name: "team-a", Type: Slack, URL: "https://hooks.slack.com/services/T0/B0/A"})
+	c.CostAllocation.Tags = []string{"team"}
+	c.CostAllocation.Routes = []Route{{Tag: tag, Value: "a", Destinations: []string{"team-a"}}}
+	c.Collectors = []string{"tags"}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		change func(c *Config)
+		// problems are found in the problems reported, one each, none meaning the configuration is valid.
+		problems []string
+	}{
+		{"valid", func(c *Config) {}, nil},
+		{"new regions", func(c *Config) {
+			c.Regions = []string{"us-east-1", "il-central-1", "ap-southeast-5", "us-gov-west-1", "us-isob-east-1", "cn-north-1"}
+		}, nil},
+		{"region case", func(c *Config) { c.Regions = []string{"US-EAST-1"} }, []string{`regions: invalid region "US-EAST-1"`}},
+		{"region without number", func(c *Config) { c.Regions = []string{"us-east"} }, []string{`regions: invalid region "us-east"`}},
+		{"global region", func(c *Config) { c.Regions = []string{"global"} }, []string{`regions: invalid region "global"`}},
+		{"no region", func(c *Config) { c.Regions = nil }, []string{"regions: at least one region is required"}},
+		{"schedule region", func(c *Config) { c.Schedules = []Schedule{{Name: "weekly", Regions: []string{"eu_west_1"}}} }, []string{`schedules[0].regions: invalid region "eu_west_1"`}},
+		{"cron", func(c *Config) { c.Schedule = "every monday" }, []string{`schedule: invalid cron definition "every monday"`}},
+		{"cron fields", func(c *Config) { c.Schedule = "0 0 61 * * *" }, []string{`schedule: invalid cron definition "0 0 61 * * *"`}},
+		{"schedule cron", func(c *Config) { c.Schedules = []Schedule{{Name: "weekly", Cron: "weekly"}} }, []string{`schedules[0].cron: invalid cron definition "weekly"`}},
+		{"alerts cron", func(c *Config) { c.Alerts.Schedule = "" }, []string{`alerts.schedule: invalid cron definition ""`}},
+		{"timezone", func(c *Config) { c.Timezone = "Asia/Singapore" }, nil},
+		{"unknown timezone", func(c *Config) { c.Timezone = "Mars/Olympus" }, []string{`timezone: unknown timezone "Mars/Olympus"`}},
+		{"no timezone", func(c *Config) { c.Timezone = "" }, []string{"timezone: the timezone is missing"}},
+		{"schedule timezone", func(c *Config) { c.Schedules = []Schedule{{Name: "weekly", Timezone: "SGT"}} }, []string{`schedules[0].timezone: unknown timezone "SGT"`}},
+		{"shortest anomaly window", func(c *Config) { c.Anomalies.Window = 3 }, nil},
+		{"longest anomaly window", func(c *Config) { c.Anomalies.Window = 56 }, nil},
+		{"anomaly window too short", func(c *Config) { c.Anomalies.Window = 2 }, []string{"anomalies.window: must be between 3 and 56 days"}},
+		{"anomaly window too long", func(c *Config) { c.Anomalies.Window = 57 }, []string{"anomalies.window: must be between 3 and 56 days"}},
+		{"missing webhook", func(c *Config) { c.Destinations[0].URL = "" }, []string{"destinations[0]: the slack web-hook URL is missing"}},
+		{"invalid webhook", func(c *Config) { c.Destinations[0].URL = "hooks.slack.com/services/T0/B0/X" }, []string{"destinations[0]: the slack web-hook URL isn't a valid http(s) URL"}},
+		{"route", func(c *Config) { route(c, "team") }, nil},
+		{"route without tags collector", func(c *Config) {
+			route(c, "team")
+			c.Collectors = nil
+		}, []string{"cost_allocation.routes: the routes slice the costs by tag, but no schedule enables the tags collector"}},
+		{"route of unknown tag", func(c *Config) { route(c, "project") }, []string{`cost_allocation.routes[0].tag: unknown tag key "project"`}},
+		{"unknown destination", func(c *Config) { c.Alerts.Destinations = []string{"pager"} }, []string{`alerts.destinations: unknown destination "pager"`}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := validConfig()
+			test.change(&c)
+			problems := problemsOf(t, c)
+			if len(problems) != len(test.problems) {
+				t.Fatalf("got problems %q, want %q", problems, test.problems)
+			}
+			for i, want := range test.problems {
+				if !strings.HasPrefix(problems[i], want) {
+					t.Errorf("got problem %q, want %q", problems[i], want)
+				}
+			}
+		})
+	}
+}
+
+func TestValidateReportsAllProblems(t *testing.T) {
+	c := validConfig()
+	c.Regions = []string{"us-east"}
+	c.Schedule = "every monday"
+	c.Timezone = "Mars/Olympus"
+	c.Anomalies.Window = 100
+	c.Destinations[0].URL = ""
+
+	problems := problemsOf(t, c)
+	want := []string{
+		"regions: invalid region",
+		"schedule: invalid cron definition",
+		"timezone: unknown timezone",
+		"destinations[0]: the slack web-hook URL is missing",
+		"anomalies.window: must be between 3 and 56 days",
+	}
+	if len(problems) != len(want) {
+		t.Fatalf("got problems %q, want one of each of %q", problems, want)
+	}
+	for _, prefix := range want {
+		found := 0
+		for _, problem := range problems {
+			if strings.HasPrefix(problem, prefix) {
+				found++
+			}
+		}
+		if found != 1 {
+			t.Errorf("got %q %d times in %q, want once", prefix, found, problems)
+		}
+	}
+}
+
+func TestRoutedDestinationsNotDefault(t *testing.T) {
+	c := validConfig()
+	c.Destinations = append(c.Destinations,
+		Destination{Name: "team-a", Type: Slack, URL: "https://hooks.slack.com/services/T0/B0/A"},
+		Destination{Name: "archive", Type: File, Path: "report.json"})
+	c.CostAllocation.Tags = []string{"team"}
+	c.CostAllocation.Routes = []Route{{Tag: "team", Value: "a", Destinations: []string{"team-a"}}}
+	c.Collectors = []string{"tags"}
+	c.Anomalies.Schedule = "0 0 9 * * *"
+	above := 100.0
+	c.Rules = []Rule{{Name: "Charges", Service: "billing", Metric: "Accumulated This Month", Above: &above, Alert: true}}
+	if problems := problemsOf(t, c); len(problems) > 0 {
+		t.Fatalf("got problems %q, want none", problems)
+	}
+
+	want := []string{"slack", "archive"}
+	if got := c.ResolvedSchedules()[0].Destinations; !reflect.DeepEqual(got, want) {
+		t.Errorf("got schedule destinations %q, want %q", got, want)
+	}
+	alerts, ok := c.AlertChecks()
+	if !ok || !reflect.DeepEqual(alerts.Destinations, want) {
+		t.Errorf("got alert destinations %q, want %q", alerts.Destinations, want)
+	}
+	anomalies, ok := c.AnomalyChecks()
+	if !ok || !reflect.DeepEqual(anomalies.Destinations, want) {
+		t.Errorf("got anomaly destinations %q, want %q", anomalies.Destinations, want)
+	}
+
+	// A schedule naming a routed destination still sends it the whole report.
+	c.Schedules = []Schedule{{Name: "weekly", Destinations: []string{"team-a"}}}
+	if got := c.ResolvedSchedules()[0].Destinations; !reflect.DeepEqual(got, []string{"team-a"}) {
+		t.Errorf("got schedule destinations %q, want the team-a it names", got)
+	}
+}
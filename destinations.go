@@ -0,0 +1,28 @@
+package main
+
+import (
+	"github.com/WUMUXIAN/aws-slack-bot/config"
+	"github.com/WUMUXIAN/aws-slack-bot/notify"
+	"github.com/WUMUXIAN/aws-slack-bot/render"
+)
+
+// newNotifier creates the notifier of a validated destination.
+func newNotifier(d config.Destination) notify.Notifier {
+	renderer, _ := render.New(d.FormatOf())
+	switch d.Type {
+	case config.Email:
+		return notify.NewEmail(d.Name, d.Addr, d.Username, d.Password, d.From, d.To, renderer)
+	case config.File:
+		return notify.NewFile(d.Name, d.Path, renderer)
+	}
+	return notify.NewWebhook(d.Name, d.URL, renderer)
+}
+
+// newNotifiers creates the notifiers of all validated destinations.
+func newNotifiers(destinations []config.Destination) []notify.Notifier {
+	notifiers := make([]notify.Notifier, 0, len(destinations))
+	for _, d := range destinations {
+		notifiers = append(notifiers, newNotifier(d))
+	}
+	return notifiers
+}
@@ -0,0 +1,74 @@
+// Package forecast projects the charges of a month from its daily charges so far.
+package forecast
+
+import (
+	"math"
+)
+
+// halfLife is the age in days at which a daily spend weighs half as much in the trend-weighted forecast.
+const halfLife = 7
+
+// confidence is the z-score of the confidence band, about 95% of the outcomes.
+const confidence = 1.96
+
+// Forecast is the projected charges at the end of a month.
+type Forecast struct {
+	// Linear carries the average daily spend so far over to the rest of the month.
+	Linear float64
+	// Trend carries a daily spend weighted towards the latest days over to the rest of the month.
+	Trend float64
+	// Margin is how far from a forecast the charges may end up, given how much the daily spend varies.
+	Margin float64
+	// Accumulated is the latest charges, below which no forecast goes.
+	Accumulated float64
+}
+
+// Band returns the confidence band of a forecast, which never goes below the accumulated charges.
+func (o Forecast) Band(forecast float64) (low, high float64) {
+	return math.Max(o.Accumulated, forecast-o.Margin), forecast + o.Margin
+}
+
+// Month projects the charges at the end of a month of given days from the month to date charges of each of its days,
+// in chronological order, of which elapsed days have passed. The latest day counts only if it is complete.
+// It returns false if not even one day is complete.
+func Month(charges []float64, elapsed, days float64) (Forecast, bool) {
+	complete := int(math.Floor(elapsed))
+	if complete > len(charges) {
+		complete = len(charges)
+	}
+	if complete < 1 || elapsed <= 0 {
+		return Forecast{}, false
+	}
+
+	// The daily spends are the increments of the charges, which start from 0 with the month.
+	spends := make([]float64, 0, complete)
+	previous := 0.0
+	for _, value := range charges[:complete] {
+		spends = append(spends, math.Max(0, value-previous))
+		previous = value
+	}
+
+	accumulated := charges[len(charges)-1]
+	remaining := math.Max(0, days-elapsed)
+
+	var weighted, weights, sum float64
+	for i, spend := range spends {
+		weight := math.Pow(2, -float64(len(spends)-1-i)/halfLife)
+		weighted += weight * spend
+		weights += weight
+		sum += spend
+	}
+	mean := sum / float64(len(spends))
+	variance := 0.0
+	for _, spend := range spends {
+		variance += (spend - mean) * (spend - mean)
+	}
+	variance /= float64(len(spends))
+
+	return Forecast{
+		Linear:      accumulated / elapsed * days,
+		Trend:       accumulated + weighted/weights*remaining,
+		Margin:      confidence * math.Sqrt(variance*remaining),
+		Accumulated: accumulated,
+	}, true
+}
@@ -0,0 +1,89 @@
+package forecast
+
+import (
+	"math"
+	"testing"
+)
+
+// near tells whether two amounts are the same to a thousandth of a cent.
+func near(a, b float64) bool {
+	return math.Abs(a-b) < 1e-5
+}
+
+func TestMonth(t *testing.T) {
+	tests := []struct {
+		name    string
+		charges []float64
+		elapsed float64
+		days    float64
+		ok      bool
+		linear  float64
+		trend   float64
+		margin  float64
+	}{
+		{"flat", []float64{10, 20, 30, 40, 50, 60, 70, 80, 90, 100}, 10, 30, true, 300, 300, 0},
+		{"first day", []float64{4}, 0.5, 31, false, 0, 0, 0},
+		{"first complete day", []float64{4}, 1, 31, true, 124, 124, 0},
+		{"no charges", []float64{}, 3, 30, false, 0, 0, 0},
+		// The partial day counts in the charges, but not as a day of lower spend.
+		{"partial last day", []float64{10, 20, 25}, 2.5, 30, true, 300, 300, 0},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			forecast, ok := Month(test.charges, test.elapsed, test.days)
+			if ok != test.ok {
+				t.Fatalf("got %t, want %t", ok, test.ok)
+			}
+			if !ok {
+				return
+			}
+			if !near(forecast.Linear, test.linear) {
+				t.Errorf("got linear forecast %f, want %f", forecast.Linear, test.linear)
+			}
+			if !near(forecast.Trend, test.trend) {
+				t.Errorf("got trend forecast %f, want %f", forecast.Trend, test.trend)
+			}
+			if !near(forecast.Margin, test.margin) {
+				t.Errorf("got margin %f, want %f", forecast.Margin, test.margin)
+			}
+			if accumulated := test.charges[len(test.charges)-1]; forecast.Accumulated != accumulated {
+				t.Errorf("got accumulated charges %f, want %f", forecast.Accumulated, accumulated)
+			}
+		})
+	}
+}
+
+func TestMonthRising(t *testing.T) {
+	// The daily spend rises by a dollar a day, from 1 to 10.
+	charges := make([]float64, 0, 10)
+	total := 0.0
+	for spend := 1.0; spend <= 10; spend++ {
+		total += spend
+		charges = append(charges, total)
+	}
+	forecast, ok := Month(charges, 10, 30)
+	if !ok {
+		t.Fatal("got no forecast")
+	}
+	if !near(forecast.Linear, 165) {
+		t.Errorf("got linear forecast %f, want 165", forecast.Linear)
+	}
+	// The latest days weigh more, so the trend is above the average daily spend but below the latest one.
+	if forecast.Trend <= 55+5.5*20 || forecast.Trend >= 55+10*20 {
+		t.Errorf("got trend forecast %f, want between %f and %f", forecast.Trend, 55+5.5*20, 55+10*20.0)
+	}
+	if forecast.Margin <= 0 {
+		t.Errorf("got margin %f, want a positive margin as the daily spend varies", forecast.Margin)
+	}
+}
+
+func TestBand(t *testing.T) {
+	forecast := Forecast{Linear: 120, Trend: 150, Margin: 100, Accumulated: 40}
+	if low, high := forecast.Band(forecast.Trend); low != 50 || high != 250 {
+		t.Errorf("got band %f-%f, want 50-250", low, high)
+	}
+	// The band never goes below the charges already accumulated.
+	if low, high := forecast.Band(forecast.Linear); low != 40 || high != 220 {
+		t.Errorf("got band %f-%f, want 40-220", low, high)
+	}
+}
@@ -0,0 +1,142 @@
+// Package format turns report values into human readable text.
+package format
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/WUMUXIAN/aws-slack-bot/report"
+)
+
+// Value formats the value of a metric according to its unit, followed by its band, share and changes if any,
+// e.g. "12 (+3 ▲, -1 ▼ w/w)", "$1200.00 USD [$1100.00 USD – $1300.00 USD] (+$100.00 USD ▲ m/m)"
+// or "$120.00 USD, 34.2% (-$10.00 USD ▼ m/m)".
+func Value(m report.Metric) string {
+	value := Amount(m.Value, m.Unit)
+	if m.Band != nil {
+		value += " [" + Amount(m.Band.Low, m.Unit) + " – " + Amount(m.Band.High, m.Unit) + "]"
+	}
+	if m.Share > 0 {
+		value += fmt.Sprintf(", %.1f%%", m.Share)
+	}
+	if len(m.Changes) == 0 {
+		return value
+	}
+	changes := make([]string, 0, len(m.Changes))
+	for _, change := range m.Changes {
+		text := Delta(m.Delta(change), m.Unit)
+		switch change.Since {
+		case report.SinceLastWeek:
+			text += " w/w"
+		case report.SinceLastMonth:
+			text += " m/m"
+		}
+		changes = append(changes, text)
+	}
+	return value + " (" + strings.Join(changes, ", ") + ")"
+}
+
+// Amount formats a value according to its unit.
+func Amount(value float64, unit report.Unit) string {
+	switch unit {
+	case report.Count:
+		return fmt.Sprintf("%.0f", value)
+	case report.CountPerDay:
+		return fmt.Sprintf("%0.2f/Day", value)
+	case report.CountPerSecond:
+		return fmt.Sprintf("%0.2f/Second", value)
+	case report.Bytes:
+		return Storage(value)
+	case report.BytesPerDay:
+		return fmt.Sprintf("%s/Day", Storage(value))
+	case report.BytesPerSecond:
+		return fmt.Sprintf("%s/Second", Storage(value))
+	case report.Percent:
+		return fmt.Sprintf("%0.2f%%", value)
+	case report.Days:
+		return fmt.Sprintf("%.0f days", value)
+	case report.USD:
+		return fmt.Sprintf("$%.02f USD", value)
+	}
+	return fmt.Sprintf("%0.2f %s", value, unit)
+}
+
+// Delta formats a signed change of a value with an arrow showing its direction, e.g. "+3 ▲".
+func Delta(delta float64, unit report.Unit) string {
+	switch {
+	case delta > 0:
+		return "+" + Amount(delta, unit) + " ▲"
+	case delta < 0:
+		return "-" + Amount(-delta, unit) + " ▼"
+	}
+	return "±" + Amount(0, unit)
+}
+
+// maxProblemMessage is the number of characters of a problem message that are kept.
+const maxProblemMessage = 200
+
+// Problem describes a collection problem in one line, e.g.
+// "ec2 in us-east-1: ec2:DescribeInstances failed with AccessDenied, <reason>".
+func Problem(p report.Problem) string {
+	message := p.Message
+	if runes := []rune(message); len(runes) > maxProblemMessage {
+		message = string(runes[:maxProblemMessage-3]) + "..."
+	}
+	where := p.Service
+	if p.Region != "" {
+		where += " in " + p.Region
+	}
+	if p.Account != "" {
+		where += " of " + p.Account
+	}
+	if p.API == "" {
+		return fmt.Sprintf("%s: %s, %s", where, p.Code, message)
+	}
+	return fmt.Sprintf("%s: %s failed with %s, %s", where, p.API, p.Code, message)
+}
+
+// Alert describes an alert in one line, followed by its detail if any, e.g.
+// "RDS CPU (critical): rds CPU in us-east-1 is 85.00%, above 80.00%".
+func Alert(a report.Alert) string {
+	where := a.Metric.Service + " " + a.Metric.Label()
+	if a.Metric.Region != "" {
+		where += " in " + a.Metric.Region
+	}
+	if a.Metric.Account != "" {
+		where += " of " + a.Metric.Account
+	}
+	text := fmt.Sprintf("%s (%s): %s is %s, %s %s", a.Rule, a.Severity, where,
+		Amount(a.Metric.Value, a.Metric.Unit), a.Condition, Amount(a.Threshold, a.Metric.Unit))
+	if a.Detail != "" {
+		text += " (" + a.Detail + ")"
+	}
+	return text
+}
+
+// AccountEvent describes an event of an account of the organization in one line, e.g.
+// "sandbox (123456789012) joined the organization".
+func AccountEvent(e report.AccountEvent) string {
+	what := map[string]string{
+		report.Joined:    "joined the organization",
+		report.Suspended: "was suspended",
+		report.Left:      "left the organization",
+	}[e.Event]
+	if e.Account == e.ID {
+		return e.ID + " " + what
+	}
+	return fmt.Sprintf("%s (%s) %s", e.Account, e.ID, what)
+}
+
+// Storage formats a size in bytes using the largest fitting unit.
+func Storage(bytes float64) string {
+	if bytes >= 1024*1024*1024*1024 {
+		return fmt.Sprintf("%0.2f TB", bytes/(1024*1024*1024*1024))
+	} else if bytes > 1024*1024*1024 {
+		return fmt.Sprintf("%0.2f GB", bytes/(1024*1024*1024))
+	} else if bytes > 1024*1024 {
+		return fmt.Sprintf("%0.2f MB", bytes/(1024*1024))
+	} else if bytes > 1024 {
+		return fmt.Sprintf("%0.2f KB", bytes/(1024))
+	}
+	return fmt.Sprintf("%0.2f Bytes", bytes)
+}
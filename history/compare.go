@@ -0,0 +1,57 @@
+package history
+
+import (
+	"github.com/WUMUXIAN/aws-slack-bot/report"
+)
+
+// compareDays is how far back the earlier reports are looked for.
+const compareDays = 8
+
+// Compare fills in the changes of the report's metrics since the previous report
+// and since the same day last week, in the timezone the report was generated in,
+// from the records kept in the store before the report.
+// A metric accumulated to date is only compared with the records of the same period, e.g. not the month to date charges
+// on the 1st of the month with the ones of the whole month before, while the others are whatever their period.
+func Compare(store Store, r *report.Report) error {
+	records, err := store.Query(Query{
+		From: r.GeneratedAt.AddDate(0, 0, -compareDays),
+		To:   r.GeneratedAt,
+	})
+	if err != nil {
+		return err
+	}
+
+	// The records being oldest first, the latest of each key wins.
+	location := r.GeneratedAt.Location()
+	year, month, day := r.GeneratedAt.AddDate(0, 0, -7).Date()
+	previous := make(map[Key]Record)
+	lastWeek := make(map[Key]Record)
+	for _, record := range records {
+		if !record.Timestamp.Before(r.GeneratedAt) {
+			continue
+		}
+		previous[record.Key] = record
+		if y, m, d := record.Timestamp.In(location).Date(); y == year && m == month && d == day {
+			lastWeek[record.Key] = record
+		}
+	}
+
+	for i := range r.Sections {
+		for j := range r.Sections[i].Metrics {
+			metric := &r.Sections[i].Metrics[j]
+			key := KeyOf(*metric)
+			if record, ok := previous[key]; ok && (!metric.ToDate || samePeriod(record.Period, metric.Period)) {
+				metric.Changes = append(metric.Changes, report.Change{Since: report.SincePrevious, Value: record.Value, At: record.Timestamp})
+			}
+			if record, ok := lastWeek[key]; ok && (!metric.ToDate || samePeriod(record.Period, metric.Period)) {
+				metric.Changes = append(metric.Changes, report.Change{Since: report.SinceLastWeek, Value: record.Value, At: record.Timestamp})
+			}
+		}
+	}
+	return nil
+}
+
+// samePeriod tells whether two periods are the same, whatever their timezone.
+func samePeriod(a, b report.Period) bool {
+	return a.Start.Equal(b.Start) && a.End.Equal(b.End)
+}
@@ -0,0 +1,83 @@
+package history
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/WUMUXIAN/aws-slack-bot/report"
+)
+
+// reportAt returns a report generated at a time with a gauge, the running instances,
+// and a metric accumulated to date, the charges of the month, of the same value.
+func reportAt(at time.Time, value float64) report.Report {
+	period := report.MonthOf(at)
+	return report.Report{GeneratedAt: at, Period: period, Sections: []report.Section{{Metrics: []report.Metric{
+		{Service: "ec2", Region: "us-east-1", Name: "Running Instances", Value: value, Unit: report.Count, Period: period},
+		{Service: "billing", Region: "us-east-1", Name: "Accumulated This Month", Value: value, Unit: report.USD, Period: period, ToDate: true},
+	}}}}
+}
+
+func TestCompare(t *testing.T) {
+	at := func(month time.Month, day int) time.Time {
+		return time.Date(2026, month, day, 9, 0, 0, 0, time.UTC)
+	}
+	tests := []struct {
+		name string
+		at   time.Time
+		// earlier are the values of the reports before, by the day they were generated on.
+		earlier map[time.Time]float64
+		// gauge and toDate are the changes of the running instances and of the charges, previous first.
+		gauge  []report.Change
+		toDate []report.Change
+	}{
+		{"same month", at(10, 17), map[time.Time]float64{at(10, 10): 4, at(10, 16): 9},
+			[]report.Change{{Since: report.SincePrevious, Value: 9, At: at(10, 16)}, {Since: report.SinceLastWeek, Value: 4, At: at(10, 10)}},
+			[]report.Change{{Since: report.SincePrevious, Value: 9, At: at(10, 16)}, {Since: report.SinceLastWeek, Value: 4, At: at(10, 10)}}},
+		// On the 1st, the charges of the month so far aren't compared with the whole month before, unlike the instances.
+		{"first of the month", at(11, 1), map[time.Time]float64{at(10, 25): 4, at(10, 31): 9},
+			[]report.Change{{Since: report.SincePrevious, Value: 9, At: at(10, 31)}, {Since: report.SinceLastWeek, Value: 4, At: at(10, 25)}},
+			nil},
+		// In the first week, only the charges of last week are of another month.
+		{"first week", at(11, 5), map[time.Time]float64{at(10, 29): 4, at(11, 4): 9},
+			[]report.Change{{Since: report.SincePrevious, Value: 9, At: at(11, 4)}, {Since: report.SinceLastWeek, Value: 4, At: at(10, 29)}},
+			[]report.Change{{Since: report.SincePrevious, Value: 9, At: at(11, 4)}}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dir, err := ioutil.TempDir("", "history")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.RemoveAll(dir)
+			store := NewFiles(dir)
+			for day, value := range test.earlier {
+				if err := store.Append(Records(reportAt(day, value))); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			r := reportAt(test.at, 12)
+			if err := Compare(store, &r); err != nil {
+				t.Fatal(err)
+			}
+			metrics := r.Sections[0].Metrics
+			checkChanges(t, metrics[0], test.gauge)
+			checkChanges(t, metrics[1], test.toDate)
+		})
+	}
+}
+
+// checkChanges checks the changes of a metric, whatever their timezone.
+func checkChanges(t *testing.T, metric report.Metric, want []report.Change) {
+	if len(metric.Changes) != len(want) {
+		t.Errorf("%s: got changes %v, want %v", metric.Name, metric.Changes, want)
+		return
+	}
+	for i, change := range metric.Changes {
+		if change.Since != want[i].Since || change.Value != want[i].Value || !change.At.Equal(want[i].At) {
+			t.Errorf("%s: got change %v, want %v", metric.Name, change, want[i])
+		}
+	}
+}
@@ -0,0 +1,188 @@
+package history
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// dayLayout names the file of each day.
+const dayLayout = "2006-01-02"
+
+// membersFile is the file of the member accounts found by each job.
+const membersFile = "members.json"
+
+// Files stores records as JSON lines, in a file per UTC day named e.g. 2018-07-31.jsonl,
+// and the member accounts found by each job in members.json.
+type Files struct {
+	Dir string
+	// mu serialises the appends of jobs running at the same time.
+	mu *sync.Mutex
+}
+
+// NewFiles creates a store keeping its files in dir.
+func NewFiles(dir string) Files {
+	return Files{
+		Dir: dir,
+		mu:  new(sync.Mutex),
+	}
+}
+
+// Append implements Store.
+func (o Files) Append(records []Record) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if err := os.MkdirAll(o.Dir, 0755); err != nil {
+		return err
+	}
+	days := make(map[string][]Record)
+	for _, record := range records {
+		day := record.Timestamp.UTC().Format(dayLayout)
+		days[day] = append(days[day], record)
+	}
+	for day, records := range days {
+		if err := o.appendDay(day, records); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// appendDay appends the records to the file of a day, writing them at once.
+func (o Files) appendDay(day string, records []Record) error {
+	lines := make([]byte, 0)
+	for _, record := range records {
+		line, err := json.Marshal(record)
+		if err != nil {
+			return err
+		}
+		lines = append(append(lines, line...), '\n')
+	}
+	file, err := os.OpenFile(filepath.Join(o.Dir, day+".jsonl"), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(lines); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// Query implements Store, reading only the files of the days queried.
+func (o Files) Query(q Query) ([]Record, error) {
+	infos, err := ioutil.ReadDir(o.Dir)
+	if os.IsNotExist(err) {
+		return []Record{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	records := make([]Record, 0)
+	for _, info := range infos {
+		day, err := time.Parse(dayLayout, strings.TrimSuffix(info.Name(), ".jsonl"))
+		if err != nil || info.IsDir() || !strings.HasSuffix(info.Name(), ".jsonl") {
+			continue
+		}
+		if (!q.From.IsZero() && day.AddDate(0, 0, 1).Before(q.From)) || (!q.To.IsZero() && day.After(q.To)) {
+			continue
+		}
+		dayRecords, err := o.readDay(filepath.Join(o.Dir, info.Name()), q)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, dayRecords...)
+	}
+	sort.SliceStable(records, func(i, j int) bool {
+		return records[i].Timestamp.Before(records[j].Timestamp)
+	})
+	return records, nil
+}
+
+// readDay reads the records of a file selected by the query.
+func (o Files) readDay(path string, q Query) ([]Record, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	records := make([]Record, 0)
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var record Record
+		// A line cut short by a crash is skipped rather than making the whole history unreadable.
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			continue
+		}
+		if q.Match(record) {
+			records = append(records, record)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read %s: %s", path, err.Error())
+	}
+	return records, nil
+}
+
+// Members implements MemberStore.
+func (o Files) Members(job string) ([]Member, bool, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	byJob, err := o.readMembers()
+	if err != nil {
+		return nil, false, err
+	}
+	members, ok := byJob[job]
+	return members, ok, nil
+}
+
+// SetMembers implements MemberStore, replacing the file at once so that a crash doesn't leave it cut short.
+func (o Files) SetMembers(job string, members []Member) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	byJob, err := o.readMembers()
+	if err != nil {
+		return err
+	}
+	byJob[job] = members
+	content, err := json.MarshalIndent(byJob, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(o.Dir, 0755); err != nil {
+		return err
+	}
+	path := filepath.Join(o.Dir, membersFile)
+	if err := ioutil.WriteFile(path+".tmp", content, 0644); err != nil {
+		return err
+	}
+	return os.Rename(path+".tmp", path)
+}
+
+// readMembers reads the member accounts found by each job, none if the file doesn't exist yet.
+func (o Files) readMembers() (map[string][]Member, error) {
+	byJob := make(map[string][]Member)
+	content, err := ioutil.ReadFile(filepath.Join(o.Dir, membersFile))
+	if os.IsNotExist(err) {
+		return byJob, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(content, &byJob); err != nil {
+		return nil, fmt.Errorf("failed to read %s: %s", membersFile, err.Error())
+	}
+	return byJob, nil
+}
@@ -0,0 +1,100 @@
+// Package history persists the metrics of every report, so that later reports can be compared with them
+// and what was reported on a given day can be audited.
+package history
+
+import (
+	"time"
+
+	"github.com/WUMUXIAN/aws-slack-bot/report"
+)
+
+// Key identifies a series of records of the same metric.
+type Key struct {
+	Account string `json:"account,omitempty"`
+	Region  string `json:"region"`
+	Service string `json:"service"`
+	Metric  string `json:"metric"`
+	// Label tells apart the metrics of the same name, e.g. the size of each bucket.
+	Label string `json:"label,omitempty"`
+}
+
+// KeyOf returns the key of a metric.
+func KeyOf(m report.Metric) Key {
+	key := Key{Account: m.Account, Region: m.Region, Service: m.Service, Metric: m.Name}
+	if len(m.Dimensions) > 0 {
+		key.Label = m.Label()
+	}
+	return key
+}
+
+// Record is the value of a metric as reported at a point in time.
+type Record struct {
+	Key
+	Timestamp  time.Time         `json:"timestamp"`
+	Value      float64           `json:"value"`
+	Unit       report.Unit       `json:"unit"`
+	Dimensions map[string]string `json:"dimensions,omitempty"`
+	Period     report.Period     `json:"period"`
+}
+
+// Records returns the records of all metrics of a report, timestamped when the report was generated.
+func Records(r report.Report) []Record {
+	records := make([]Record, 0)
+	for _, section := range r.Sections {
+		for _, m := range section.Metrics {
+			records = append(records, Record{
+				Key:        KeyOf(m),
+				Timestamp:  r.GeneratedAt,
+				Value:      m.Value,
+				Unit:       m.Unit,
+				Dimensions: m.Dimensions,
+				Period:     m.Period,
+			})
+		}
+	}
+	return records
+}
+
+// Query selects records, its zero fields matching any record.
+type Query struct {
+	Key
+	From time.Time
+	To   time.Time
+}
+
+// Match reports whether the record is selected by the query.
+func (q Query) Match(r Record) bool {
+	return (q.Account == "" || q.Account == r.Account) &&
+		(q.Region == "" || q.Region == r.Region) &&
+		(q.Service == "" || q.Service == r.Service) &&
+		(q.Metric == "" || q.Metric == r.Metric) &&
+		(q.Label == "" || q.Label == r.Label) &&
+		(q.From.IsZero() || !r.Timestamp.Before(q.From)) &&
+		(q.To.IsZero() || !r.Timestamp.After(q.To))
+}
+
+// Store persists records.
+type Store interface {
+	// Append adds records to the store.
+	Append(records []Record) error
+	// Query returns the records selected by the query, oldest first.
+	Query(q Query) ([]Record, error)
+}
+
+// Member is a member account of an organization as found by a discovery.
+type Member struct {
+	ID         string `json:"id"`
+	Name       string `json:"name,omitempty"`
+	Status     string `json:"status"`
+	Management bool   `json:"management,omitempty"`
+	Selected   bool   `json:"selected,omitempty"`
+}
+
+// MemberStore persists the member accounts found by the last discovery of each job,
+// so that the accounts that joined or left since are told across runs and restarts.
+type MemberStore interface {
+	// Members returns the member accounts the job last found, false if it never discovered any.
+	Members(job string) ([]Member, bool, error)
+	// SetMembers replaces the member accounts the job last found.
+	SetMembers(job string, members []Member) error
+}
@@ -0,0 +1,257 @@
+package jobs
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"github.com/WUMUXIAN/aws-slack-bot/history"
+	"github.com/WUMUXIAN/aws-slack-bot/report"
+	"github.com/WUMUXIAN/aws-slack-bot/stats"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/credentials/stscreds"
+	"github.com/aws/aws-sdk-go/aws/session"
+)
+
+// Account is an AWS account a job watches by assuming a role in it,
+// or with the credentials as they are if no role is set.
+type Account struct {
+	// Name tells the account apart in the report.
+	Name       string
+	ID         string
+	RoleARN    string
+	ExternalID string
+	// Management is set on the management account of an organization, whose charges are those of all its members.
+	Management bool
+}
+
+// Organization discovers the accounts to watch among the members of the organization
+// whose management account the credentials belong to.
+type Organization struct {
+	// RoleName is the role assumed in the member accounts, the credentials being used as they are in the management account.
+	RoleName   string
+	ExternalID string
+	Filter     stats.OrganizationFilter
+}
+
+// sessions creates the sessions of each account and region once,
+// so that the role of an account is assumed again only when its credentials expire.
+type sessions struct {
+	mu        sync.Mutex
+	regions   []string
+	base      *session.Session
+	byAccount map[Account]map[string]*session.Session
+}
+
+// newSessions creates the sessions of the watched regions.
+func newSessions(regions []string) *sessions {
+	return &sessions{
+		regions:   regions,
+		base:      session.Must(session.NewSession(&aws.Config{Region: aws.String(stats.GlobalRegion)})),
+		byAccount: make(map[Account]map[string]*session.Session),
+	}
+}
+
+// of returns the session of an account in a region.
+func (o *sessions) of(account Account, region string) *session.Session {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	byRegion, ok := o.byAccount[account]
+	if !ok {
+		var creds *credentials.Credentials
+		if account.RoleARN != "" {
+			// The credentials are shared by the regions, so that the role is assumed once until they expire.
+			creds = stscreds.NewCredentials(o.base, account.RoleARN, func(p *stscreds.AssumeRoleProvider) {
+				p.RoleSessionName = "aws-slack-bot"
+				if account.ExternalID != "" {
+					p.ExternalID = aws.String(account.ExternalID)
+				}
+			})
+		}
+		byRegion = make(map[string]*session.Session)
+		for _, r := range o.regions {
+			byRegion[r] = session.Must(session.NewSession(&aws.Config{Region: aws.String(r), Credentials: creds}))
+		}
+		// Global collectors always run against the global region, watched or not.
+		if _, ok := byRegion[stats.GlobalRegion]; !ok {
+			byRegion[stats.GlobalRegion] = session.Must(session.NewSession(&aws.Config{Region: aws.String(stats.GlobalRegion), Credentials: creds}))
+		}
+		o.byAccount[account] = byRegion
+	}
+	return byRegion[region]
+}
+
+// knownAccounts remembers the member accounts found at the previous discovery,
+// in the store if any so that they outlive the process.
+type knownAccounts struct {
+	mu         sync.Mutex
+	store      history.MemberStore
+	job        string
+	loaded     bool
+	discovered bool
+	members    []stats.OrganizationAccount
+}
+
+// load reads the member accounts the job found before the process started, once.
+// It must be called with the lock held.
+func (o *knownAccounts) load() error {
+	if o.loaded || o.store == nil {
+		return nil
+	}
+	o.loaded = true
+	stored, ok, err := o.store.Members(o.job)
+	if err != nil || !ok {
+		return err
+	}
+	o.discovered = true
+	o.members = make([]stats.OrganizationAccount, 0, len(stored))
+	for _, member := range stored {
+		o.members = append(o.members, stats.OrganizationAccount{
+			ID:         member.ID,
+			Name:       member.Name,
+			Status:     member.Status,
+			Management: member.Management,
+			Selected:   member.Selected,
+		})
+	}
+	return nil
+}
+
+// update remembers the member accounts found and returns the events of the selected ones since the previous discovery,
+// none at the first one. An account has left once it is no longer a member at all, not as it is no longer selected.
+// An unknown account keeps the selection it had, and one not known before is left to the next discovery.
+// The events are returned along with the error of the store, if any.
+func (o *knownAccounts) update(found []stats.OrganizationAccount) ([]report.AccountEvent, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	err := o.load()
+	previous := make(map[string]stats.OrganizationAccount)
+	for _, member := range o.members {
+		previous[member.ID] = member
+	}
+	members := make([]stats.OrganizationAccount, 0, len(found))
+	current := make(map[string]bool)
+	events := make([]report.AccountEvent, 0)
+	for _, member := range found {
+		known, ok := previous[member.ID]
+		if member.Unknown {
+			if !ok && o.discovered {
+				continue
+			}
+			member.Selected = ok && known.Selected
+			member.Unknown = false
+		}
+		members = append(members, member)
+		current[member.ID] = true
+		switch {
+		case !o.discovered || !member.Selected:
+		case !ok && member.Status == stats.AccountActive:
+			events = append(events, accountEvent(member, report.Joined))
+		case ok && known.Status != stats.AccountSuspended && member.Status == stats.AccountSuspended:
+			events = append(events, accountEvent(member, report.Suspended))
+		}
+	}
+	for _, member := range o.members {
+		if member.Selected && !current[member.ID] {
+			events = append(events, accountEvent(member, report.Left))
+		}
+	}
+	o.discovered = true
+	o.members = members
+
+	if o.store != nil {
+		stored := make([]history.Member, 0, len(members))
+		for _, member := range members {
+			stored = append(stored, history.Member{
+				ID:         member.ID,
+				Name:       member.Name,
+				Status:     member.Status,
+				Management: member.Management,
+				Selected:   member.Selected,
+			})
+		}
+		if storeErr := o.store.SetMembers(o.job, stored); storeErr != nil && err == nil {
+			err = storeErr
+		}
+	}
+	return events, err
+}
+
+// last returns the member accounts found at the previous discovery, by this process or before it.
+func (o *knownAccounts) last() []stats.OrganizationAccount {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.load()
+	return o.members
+}
+
+// accountEvent records an event of a member account.
+func accountEvent(member stats.OrganizationAccount, event string) report.AccountEvent {
+	return report.AccountEvent{Account: memberName(member), ID: member.ID, Event: event}
+}
+
+// memberName returns the name of a member account, or its ID if it has none.
+func memberName(member stats.OrganizationAccount) string {
+	if member.Name != "" {
+		return member.Name
+	}
+	return member.ID
+}
+
+// accounts returns the accounts to watch, the configured ones followed by the active members the filter selects if any,
+// along with the events of the members since the previous discovery and the problems met discovering them.
+// The members are watched in the order of their names, those found at the previous discovery if they can't be listed.
+func (o SlackJob) accounts(ctx context.Context) ([]Account, []report.AccountEvent, []report.Problem) {
+	accounts := append([]Account{}, o.options.Accounts...)
+	organization := o.options.Organization
+	if organization == nil {
+		if len(accounts) == 0 {
+			// The account of the credentials, which needs no name as it is the only one.
+			accounts = append(accounts, Account{})
+		}
+		return accounts, nil, nil
+	}
+
+	var events []report.AccountEvent
+	var problems []report.Problem
+	found, err := stats.GetOrganizationAccounts(ctx, o.sessions.of(Account{}, stats.GlobalRegion), organization.Filter)
+	if err != nil {
+		problems = problemsOf(err, "", "organization", stats.GlobalRegion)
+		o.logf("Failed to discover the accounts of the organization: %s\n", err.Error())
+	}
+	// The members are listed even if the filter failed on some, those keeping the selection they had.
+	if found != nil {
+		var err error
+		if events, err = o.known.update(found); err != nil {
+			o.logf("Failed to keep the accounts of the organization: %s\n", err.Error())
+		}
+	}
+	members := append([]stats.OrganizationAccount{}, o.known.last()...)
+	sort.SliceStable(members, func(i, j int) bool {
+		return memberName(members[i]) < memberName(members[j])
+	})
+	configured := make(map[string]bool)
+	for i, account := range accounts {
+		configured[account.ID] = true
+		for _, member := range members {
+			if member.Management && member.ID == account.ID {
+				accounts[i].Management = true
+			}
+		}
+	}
+	for _, member := range members {
+		if member.Status != stats.AccountActive || !member.Selected || configured[member.ID] {
+			continue
+		}
+		account := Account{Name: memberName(member), ID: member.ID, Management: member.Management}
+		if !member.Management {
+			account.RoleARN = "arn:aws:iam::" + member.ID + ":role/" + organization.RoleName
+			account.ExternalID = organization.ExternalID
+		}
+		accounts = append(accounts, account)
+	}
+	return accounts, events, problems
+}
@@ -0,0 +1,124 @@
+package jobs
+
+import (
+	"io/ioutil"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/WUMUXIAN/aws-slack-bot/history"
+	"github.com/WUMUXIAN/aws-slack-bot/report"
+	"github.com/WUMUXIAN/aws-slack-bot/stats"
+)
+
+// member returns a member account, selected by the filter unless told otherwise.
+func member(id, status string, selected bool) stats.OrganizationAccount {
+	return stats.OrganizationAccount{ID: id, Name: "account-" + id, Status: status, Selected: selected}
+}
+
+func TestKnownAccountsUpdate(t *testing.T) {
+	discoveries := []struct {
+		name    string
+		members []stats.OrganizationAccount
+		events  []report.AccountEvent
+	}{
+		{"first", []stats.OrganizationAccount{member("1", stats.AccountActive, true), member("2", stats.AccountActive, true), member("3", stats.AccountActive, true)}, nil},
+		{"unchanged", []stats.OrganizationAccount{member("1", stats.AccountActive, true), member("2", stats.AccountActive, true), member("3", stats.AccountActive, true)}, nil},
+		{"changed", []stats.OrganizationAccount{member("1", stats.AccountActive, true), member("2", stats.AccountSuspended, true), member("4", stats.AccountActive, true), member("5", stats.AccountSuspended, true)},
+			[]report.AccountEvent{
+				{Account: "account-2", ID: "2", Event: report.Suspended},
+				{Account: "account-4", ID: "4", Event: report.Joined},
+				{Account: "account-3", ID: "3", Event: report.Left},
+			}},
+		{"still suspended", []stats.OrganizationAccount{member("1", stats.AccountActive, true), member("2", stats.AccountSuspended, true), member("4", stats.AccountActive, true)},
+			[]report.AccountEvent{{Account: "account-5", ID: "5", Event: report.Left}}},
+		// Only the selected accounts are told, and an account no longer selected hasn't left.
+		{"unselected", []stats.OrganizationAccount{member("1", stats.AccountActive, false), member("2", stats.AccountSuspended, true), member("4", stats.AccountActive, true), member("6", stats.AccountActive, false)}, nil},
+		{"unselected left", []stats.OrganizationAccount{member("2", stats.AccountSuspended, true), member("4", stats.AccountActive, true)}, nil},
+	}
+	known := &knownAccounts{}
+	for _, discovery := range discoveries {
+		events, err := known.update(discovery.members)
+		if err != nil {
+			t.Fatalf("%s discovery: %s", discovery.name, err.Error())
+		}
+		if len(events) != len(discovery.events) || len(events) > 0 && !reflect.DeepEqual(events, discovery.events) {
+			t.Errorf("%s discovery: got events %v, want %v", discovery.name, events, discovery.events)
+		}
+		if !reflect.DeepEqual(known.last(), discovery.members) {
+			t.Errorf("%s discovery: got last members %v, want %v", discovery.name, known.last(), discovery.members)
+		}
+	}
+}
+
+func TestKnownAccountsUpdateUnknown(t *testing.T) {
+	unknown := func(id, status string) stats.OrganizationAccount {
+		member := member(id, status, false)
+		member.Unknown = true
+		return member
+	}
+	known := &knownAccounts{}
+	// At the first discovery, an unknown account is remembered as not selected.
+	if _, err := known.update([]stats.OrganizationAccount{member("1", stats.AccountActive, true), member("2", stats.AccountActive, false), unknown("3", stats.AccountActive)}); err != nil {
+		t.Fatal(err)
+	}
+	want := []stats.OrganizationAccount{member("1", stats.AccountActive, true), member("2", stats.AccountActive, false), member("3", stats.AccountActive, false)}
+	if !reflect.DeepEqual(known.last(), want) {
+		t.Errorf("got last members %v, want %v", known.last(), want)
+	}
+
+	// The unknown accounts keep their selection, their status being told, and a new one is left to the next discovery.
+	events, err := known.update([]stats.OrganizationAccount{unknown("1", stats.AccountSuspended), member("2", stats.AccountActive, false), member("3", stats.AccountActive, true), unknown("4", stats.AccountActive)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []report.AccountEvent{{Account: "account-1", ID: "1", Event: report.Suspended}}; !reflect.DeepEqual(events, want) {
+		t.Errorf("got events %v, want %v", events, want)
+	}
+	want = []stats.OrganizationAccount{member("1", stats.AccountSuspended, true), member("2", stats.AccountActive, false), member("3", stats.AccountActive, true)}
+	if !reflect.DeepEqual(known.last(), want) {
+		t.Errorf("got last members %v, want %v", known.last(), want)
+	}
+
+	events, err = known.update([]stats.OrganizationAccount{member("1", stats.AccountSuspended, true), member("2", stats.AccountActive, false), member("3", stats.AccountActive, true), member("4", stats.AccountActive, true)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []report.AccountEvent{{Account: "account-4", ID: "4", Event: report.Joined}}; !reflect.DeepEqual(events, want) {
+		t.Errorf("got events %v, want the unknown account joining once selected %v", events, want)
+	}
+}
+
+func TestKnownAccountsUpdateStored(t *testing.T) {
+	dir, err := ioutil.TempDir("", "members")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	store := history.NewFiles(dir)
+
+	first := &knownAccounts{store: store, job: "daily"}
+	if _, err := first.update([]stats.OrganizationAccount{member("1", stats.AccountActive, true), member("2", stats.AccountActive, true)}); err != nil {
+		t.Fatal(err)
+	}
+
+	// A restarted process tells the changes since the members stored, not the first discovery again.
+	restarted := &knownAccounts{store: store, job: "daily"}
+	if last := restarted.last(); len(last) != 2 {
+		t.Errorf("got %d last members, want the 2 stored", len(last))
+	}
+	events, err := restarted.update([]stats.OrganizationAccount{member("1", stats.AccountActive, true), member("3", stats.AccountActive, true)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []report.AccountEvent{{Account: "account-3", ID: "3", Event: report.Joined}, {Account: "account-2", ID: "2", Event: report.Left}}
+	if !reflect.DeepEqual(events, want) {
+		t.Errorf("got events %v, want %v", events, want)
+	}
+
+	// Another job keeps its own members.
+	other := &knownAccounts{store: store, job: "weekly"}
+	if events, err := other.update([]stats.OrganizationAccount{member("4", stats.AccountActive, true)}); err != nil || len(events) != 0 {
+		t.Errorf("got events %v and error %v at the first discovery of another job, want none", events, err)
+	}
+}
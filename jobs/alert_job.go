@@ -0,0 +1,82 @@
+package jobs
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/WUMUXIAN/aws-slack-bot/report"
+)
+
+// AlertTitle is the title of the alert messages.
+const AlertTitle = "AWS Usage Alert"
+
+// AlertJob checks the metrics against the rules between the scheduled reports, and right away sends
+// the alerts that are new or more severe than at the previous check.
+type AlertJob struct {
+	job   SlackJob
+	fired *firedAlerts
+}
+
+// firedAlerts remembers the severity of the alerts sent, so that they aren't sent at every check.
+type firedAlerts struct {
+	mu       sync.Mutex
+	severity map[string]report.Severity
+}
+
+// NewAlertJob creates a job checking the rules of a slack job against the metrics it collects.
+func NewAlertJob(job SlackJob) AlertJob {
+	job.options.Title = AlertTitle
+	return AlertJob{
+		job:   job,
+		fired: &firedAlerts{severity: make(map[string]report.Severity)},
+	}
+}
+
+// alertKey identifies the metric breaking a rule.
+func alertKey(a report.Alert) string {
+	m := a.Metric
+	return fmt.Sprintf("%s|%s|%s|%s|%s|%s", a.Rule, m.Account, m.Service, m.Region, m.Name, m.Label())
+}
+
+// newAlerts returns the alerts that are new or got more severe, and forgets the alerts no longer raised.
+func (o *firedAlerts) newAlerts(alerts []report.Alert) []report.Alert {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	raised := make(map[string]report.Severity)
+	fresh := make([]report.Alert, 0)
+	for _, alert := range alerts {
+		key := alertKey(alert)
+		if alert.Severity.Above(o.severity[key]) {
+			fresh = append(fresh, alert)
+		}
+		if alert.Severity.Above(raised[key]) {
+			raised[key] = alert.Severity
+		}
+	}
+	o.severity = raised
+	return fresh
+}
+
+// RunOnce checks the rules and sends the new alerts, if any.
+func (o AlertJob) RunOnce() error {
+	r := o.job.Report()
+	alerts := o.fired.newAlerts(r.Alerts)
+	if len(alerts) == 0 {
+		o.job.logf("No new alerts\n")
+		return nil
+	}
+	return o.job.Send(report.Report{
+		Title:       r.Title,
+		Period:      r.Period,
+		GeneratedAt: r.GeneratedAt,
+		Alerts:      alerts,
+		Sections:    make([]report.Section, 0),
+		Problems:    r.Problems,
+	})
+}
+
+// Run runs the alert cron job, unless the previous check is still in progress.
+func (o AlertJob) Run() {
+	o.job.exclusive(func() { o.RunOnce() })
+}
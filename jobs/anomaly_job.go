@@ -0,0 +1,205 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/WUMUXIAN/aws-slack-bot/anomaly"
+	"github.com/WUMUXIAN/aws-slack-bot/format"
+	"github.com/WUMUXIAN/aws-slack-bot/report"
+	"github.com/WUMUXIAN/aws-slack-bot/stats"
+)
+
+// AnomalyTitle is the title of the anomaly messages.
+const AnomalyTitle = "AWS Cost Anomaly"
+
+// AnomalyRule is the rule named by the anomaly alerts.
+const AnomalyRule = "Cost anomaly"
+
+// AnomalySettings tunes how the anomalous days are detected.
+type AnomalySettings struct {
+	// Method is anomaly.MAD or anomaly.ZScore.
+	Method string
+	// Window is the number of days before a day it is compared with.
+	Window int
+	// Threshold is the score above which a day is anomalous, the method's default if 0.
+	Threshold float64
+	// MinDelta is the difference in USD with the expected spend below which a day isn't anomalous,
+	// anomaly.DefaultMinDelta if 0.
+	MinDelta float64
+}
+
+// AnomalyJob checks the spend of the last complete day against the days before it,
+// and sends an alert naming the day and how far its spend is from the expected one.
+type AnomalyJob struct {
+	job      SlackJob
+	settings AnomalySettings
+	reported *reportedDays
+}
+
+// reportedDays remembers the days of each account already alerted on, so that they aren't sent at every check.
+type reportedDays struct {
+	mu   sync.Mutex
+	days map[string]reportedDay
+}
+
+// reportedDay is the day of an alert sent and its severity.
+type reportedDay struct {
+	day      time.Time
+	severity report.Severity
+}
+
+// NewAnomalyJob creates a job detecting the anomalous days of the spend, sending the alerts to the notifiers of a slack job.
+func NewAnomalyJob(job SlackJob, settings AnomalySettings) AnomalyJob {
+	job.options.Title = AnomalyTitle
+	if settings.Method == "" {
+		settings.Method = anomaly.MAD
+	}
+	if settings.Threshold == 0 {
+		settings.Threshold = anomaly.DefaultThresholds[settings.Method]
+	}
+	if settings.MinDelta == 0 {
+		settings.MinDelta = anomaly.DefaultMinDelta
+	}
+	return AnomalyJob{
+		job:      job,
+		settings: settings,
+		reported: &reportedDays{days: make(map[string]reportedDay)},
+	}
+}
+
+// fresh reports whether the alert is new or more severe than the one sent for the same day, and remembers it.
+// The days before the alert's are forgotten, as only the last complete day is alerted on.
+func (o *reportedDays) fresh(a report.Alert) bool {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	day := a.Metric.Period.Start
+	for key, reported := range o.days {
+		if reported.day.Before(day) {
+			delete(o.days, key)
+		}
+	}
+	key := a.Metric.Account + "|" + a.Metric.Name
+	if !a.Severity.Above(o.days[key].severity) {
+		return false
+	}
+	o.days[key] = reportedDay{day: day, severity: a.Severity}
+	return true
+}
+
+// Detect returns the alerts on the last complete day of the accounts whose spend is anomalous.
+// The spend is read within the run timeout, the failures being returned as problems,
+// or as an error if there is no account or the spend of none could be read.
+func (o AnomalyJob) Detect() ([]report.Alert, []report.Problem, error) {
+	ctx := context.Background()
+	if o.job.options.RunTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, o.job.options.RunTimeout)
+		defer cancel()
+	}
+	now := o.job.now()
+	year, month, day := now.Date()
+	today := time.Date(year, month, day, 0, 0, 0, 0, now.Location())
+	yesterday := today.AddDate(0, 0, -1)
+	// The day before the window only sets where the charges start from.
+	start := today.AddDate(0, 0, -o.settings.Window-2)
+
+	alerts := make([]report.Alert, 0)
+	accounts, _, problems := o.job.accounts(ctx)
+	failed := 0
+	for _, account := range accounts {
+		spends, err := stats.GetDailySpend(ctx, o.job.sessions.of(account, stats.GlobalRegion), start, today, now.Location())
+		if err != nil {
+			problems = append(problems, problemsOf(err, account.Name, "billing", stats.GlobalRegion)...)
+			failed++
+			continue
+		}
+		days := make([]anomaly.Day, 0, len(spends))
+		for _, spend := range spends {
+			days = append(days, anomaly.Day{Date: spend.Day, Spend: spend.Spend})
+		}
+		for _, detected := range anomaly.Detect(days, o.settings.Window, o.settings.Threshold, o.settings.MinDelta, o.settings.Method) {
+			if detected.Day.Date.Equal(yesterday) {
+				alert := o.alertOf(detected)
+				alert.Metric.Account = account.Name
+				alerts = append(alerts, alert)
+			}
+		}
+	}
+	if len(accounts) == 0 {
+		return nil, problems, fmt.Errorf("found no account to read the spend of")
+	}
+	if failed == len(accounts) {
+		return nil, problems, fmt.Errorf("failed to read the spend of any account")
+	}
+	return alerts, problems, nil
+}
+
+// alertOf describes an anomalous day as an alert, critical if its score is twice the threshold.
+func (o AnomalyJob) alertOf(a anomaly.Anomaly) report.Alert {
+	severity := report.Warning
+	if math.Abs(a.Score) > 2*o.settings.Threshold {
+		severity = report.Critical
+	}
+	condition := "above"
+	if a.Score < 0 {
+		condition = "below"
+	}
+	expected := "median"
+	if o.settings.Method == anomaly.ZScore {
+		expected = "mean"
+	}
+	detail := fmt.Sprintf("%s against the %d day %s, scored %.1f", format.Delta(a.Delta(), report.USD), a.Window, expected, a.Score)
+	return report.Alert{
+		Rule:      AnomalyRule,
+		Severity:  severity,
+		Condition: condition,
+		Threshold: a.Expected,
+		Metric: report.Metric{
+			Service: "billing",
+			Name:    "Daily Spend on " + a.Day.Date.Format("2006-01-02"),
+			Value:   a.Day.Spend,
+			Unit:    report.USD,
+			Period:  report.Period{Start: a.Day.Date, End: a.Day.Date.AddDate(0, 0, 1)},
+		},
+		Detail: detail,
+	}
+}
+
+// RunOnce detects the anomalous days and sends the new alerts, if any.
+// It returns an error if there is no account or the spend of none could be read.
+func (o AnomalyJob) RunOnce() error {
+	alerts, problems, err := o.Detect()
+	for _, problem := range problems {
+		o.job.logf("Failed to detect anomalies: %s\n", format.Problem(problem))
+	}
+	if err != nil {
+		return err
+	}
+	fresh := make([]report.Alert, 0, len(alerts))
+	for _, alert := range alerts {
+		if o.reported.fresh(alert) {
+			fresh = append(fresh, alert)
+		}
+	}
+	if len(fresh) == 0 {
+		o.job.logf("No new anomalies\n")
+		return nil
+	}
+	return o.job.Send(report.Report{
+		Title:       o.job.options.Title,
+		Period:      fresh[0].Metric.Period,
+		GeneratedAt: o.job.now(),
+		Alerts:      fresh,
+		Sections:    make([]report.Section, 0),
+	})
+}
+
+// Run runs the anomaly cron job, unless the previous check is still in progress.
+func (o AnomalyJob) Run() {
+	o.job.exclusive(func() { o.RunOnce() })
+}
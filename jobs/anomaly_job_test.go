@@ -0,0 +1,52 @@
+package jobs
+
+import (
+	"testing"
+	"time"
+
+	"github.com/WUMUXIAN/aws-slack-bot/report"
+)
+
+// dayAlert returns an anomaly alert on the spend of an account on a day.
+func dayAlert(account string, day time.Time, severity report.Severity) report.Alert {
+	return report.Alert{
+		Rule:     AnomalyRule,
+		Severity: severity,
+		Metric: report.Metric{
+			Account: account,
+			Service: "billing",
+			Name:    "Daily Spend on " + day.Format("2006-01-02"),
+			Period:  report.Period{Start: day, End: day.AddDate(0, 0, 1)},
+		},
+	}
+}
+
+func TestReportedDaysFresh(t *testing.T) {
+	reported := &reportedDays{days: make(map[string]reportedDay)}
+	monday := time.Date(2026, 10, 12, 0, 0, 0, 0, time.UTC)
+	tuesday := monday.AddDate(0, 0, 1)
+
+	if !reported.fresh(dayAlert("prod", monday, report.Warning)) {
+		t.Error("got a first alert not fresh")
+	}
+	if reported.fresh(dayAlert("prod", monday, report.Warning)) {
+		t.Error("got the same alert fresh again")
+	}
+	if !reported.fresh(dayAlert("dev", monday, report.Warning)) {
+		t.Error("got the alert of another account not fresh")
+	}
+	if !reported.fresh(dayAlert("prod", monday, report.Critical)) {
+		t.Error("got a more severe alert not fresh")
+	}
+	if reported.fresh(dayAlert("prod", monday, report.Warning)) {
+		t.Error("got a less severe alert fresh")
+	}
+
+	// The days before the latest alerted on are forgotten.
+	if !reported.fresh(dayAlert("prod", tuesday, report.Warning)) {
+		t.Error("got the alert of the next day not fresh")
+	}
+	if len(reported.days) != 1 {
+		t.Errorf("got %d days remembered, want the last one only", len(reported.days))
+	}
+}
@@ -0,0 +1,73 @@
+package jobs
+
+import (
+	"github.com/WUMUXIAN/aws-slack-bot/notify"
+	"github.com/WUMUXIAN/aws-slack-bot/report"
+)
+
+// Route sends the slice of a report made of the metrics of a tag value, e.g. the cost of a team,
+// to notifiers of its own.
+type Route struct {
+	Tag       string
+	Value     string
+	Notifiers []notify.Notifier
+}
+
+// slice returns the sections of the report reduced to the metrics of the route's tag value,
+// with the alerts on them, or false if there is none.
+func (o Route) slice(r report.Report) (report.Report, bool) {
+	sliced := report.Report{
+		Title:       r.Heading() + " – " + o.Tag + ": " + o.Value,
+		Period:      r.Period,
+		GeneratedAt: r.GeneratedAt,
+		Sections:    make([]report.Section, 0),
+	}
+	for _, section := range r.Sections {
+		metrics := make([]report.Metric, 0)
+		for _, metric := range section.Metrics {
+			if o.matches(metric) {
+				metrics = append(metrics, metric)
+			}
+		}
+		if len(metrics) > 0 {
+			section.Metrics = metrics
+			sliced.Sections = append(sliced.Sections, section)
+		}
+	}
+	for _, alert := range r.Alerts {
+		if o.matches(alert.Metric) {
+			sliced.Alerts = append(sliced.Alerts, alert)
+		}
+	}
+	return sliced, len(sliced.Sections) > 0
+}
+
+// matches tells whether the metric is of the route's tag value.
+func (o Route) matches(metric report.Metric) bool {
+	value, ok := metric.Dimensions[o.Tag]
+	return ok && value == o.Value
+}
+
+// sendRoutes delivers the slices of a report to the notifiers of their routes, all within a run timeout,
+// and returns the number of notifiers that failed.
+func (o SlackJob) sendRoutes(r report.Report) int {
+	ctx, cancel := o.sendContext()
+	defer cancel()
+	failed := 0
+	for _, route := range o.options.Routes {
+		sliced, ok := route.slice(r)
+		if !ok {
+			continue
+		}
+		o.logf("Sending the report of %s %s to %d destination(s)\n", route.Tag, route.Value, len(route.Notifiers))
+		for _, result := range notify.Fanout(ctx, route.Notifiers, sliced) {
+			if result.Err != nil {
+				o.logf("Failed to send the report of %s %s to %s: %s\n", route.Tag, route.Value, result.Destination, result.Err.Error())
+				failed++
+				continue
+			}
+			o.logf("Sent the report of %s %s to %s in %s\n", route.Tag, route.Value, result.Destination, result.Duration)
+		}
+	}
+	return failed
+}
@@ -0,0 +1,58 @@
+package jobs
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/WUMUXIAN/aws-slack-bot/report"
+)
+
+// teamCost returns the cost of a team as the tags collector reports it.
+func teamCost(team string, cost float64) report.Metric {
+	return report.Metric{Service: "tags", Name: "Cost by team", Value: cost, Unit: report.USD, Dimensions: map[string]string{"team": team}}
+}
+
+func TestRouteSlice(t *testing.T) {
+	period := report.MonthOf(time.Date(2026, 10, 17, 0, 0, 0, 0, time.UTC))
+	payments, search := teamCost("payments", 120), teamCost("search", 80)
+	r := report.Report{
+		Period: period,
+		Sections: []report.Section{
+			{Service: "billing", Title: "Billing", Global: true, Metrics: []report.Metric{{Service: "billing", Name: "Accumulated This Month", Value: 200, Unit: report.USD}}},
+			{Service: "tags", Title: "Cost by Tag", Global: true, Metrics: []report.Metric{teamCost("(untagged)", 10), payments, search}},
+		},
+		Alerts: []report.Alert{
+			{Rule: "Payments budget", Severity: report.Warning, Condition: "above", Threshold: 100, Metric: payments},
+			{Rule: "Charges", Severity: report.Warning, Condition: "above", Threshold: 150, Metric: report.Metric{Service: "billing", Name: "Accumulated This Month", Value: 200}},
+		},
+	}
+
+	sliced, ok := Route{Tag: "team", Value: "payments"}.slice(r)
+	if !ok {
+		t.Fatal("got no slice, want the payments costs")
+	}
+	if want := r.Heading() + " – team: payments"; sliced.Title != want {
+		t.Errorf("got title %q, want %q", sliced.Title, want)
+	}
+	if len(sliced.Sections) != 1 || sliced.Sections[0].Service != "tags" {
+		t.Fatalf("got sections %v, want the tags section only", sliced.Sections)
+	}
+	if want := []report.Metric{payments}; !reflect.DeepEqual(sliced.Sections[0].Metrics, want) {
+		t.Errorf("got metrics %v, want %v", sliced.Sections[0].Metrics, want)
+	}
+	if len(sliced.Alerts) != 1 || sliced.Alerts[0].Rule != "Payments budget" {
+		t.Errorf("got alerts %v, want the payments budget only", sliced.Alerts)
+	}
+	// The report sliced is left whole.
+	if len(r.Sections[1].Metrics) != 3 {
+		t.Errorf("got %d metrics in the report, want 3", len(r.Sections[1].Metrics))
+	}
+
+	if _, ok := (Route{Tag: "team", Value: "infra"}).slice(r); ok {
+		t.Error("got a slice of a team without costs, want none")
+	}
+	if _, ok := (Route{Tag: "project", Value: "payments"}).slice(r); ok {
+		t.Error("got a slice of another tag's value, want none")
+	}
+}
@@ -2,316 +2,432 @@
 package jobs
 
 import (
-	"encoding/json"
+	"context"
 	"fmt"
-	"io/ioutil"
-	"net/http"
-	"strings"
+	"io"
+	"os"
+	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/WUMUXIAN/aws-slack-bot/format"
+	"github.com/WUMUXIAN/aws-slack-bot/history"
+	"github.com/WUMUXIAN/aws-slack-bot/notify"
+	"github.com/WUMUXIAN/aws-slack-bot/report"
+	"github.com/WUMUXIAN/aws-slack-bot/rules"
 	"github.com/WUMUXIAN/aws-slack-bot/stats"
-	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/aws/endpoints"
 	"github.com/aws/aws-sdk-go/aws/session"
 )
 
-// RegionUsage represents the various resource usage of a region.
-type RegionUsage struct {
-	Sess                              *session.Session
-	ec2UsageChan                      chan map[string]string
-	s3UsageChan                       chan map[string]string
-	cloudFrontUsageChan               chan map[string]string
-	elasticacheUsageChan              chan map[string]string
-	rdsUsageChan                      chan map[string]string
-	billingEstimationCurrentMonthChan chan []float64
-	billingEstimationLastMonthChan    chan []float64
+// Options tunes how a slack job runs.
+type Options struct {
+	// Name tells the job apart in the logs when several are scheduled.
+	Name string
+	// Title is the title of the report, report.DefaultTitle if empty.
+	Title string
+	// RunTimeout bounds the time spent collecting a report, and then the time spent delivering it, 0 meaning no limit.
+	RunTimeout time.Duration
+	// CollectorTimeout bounds the time a collector spends in a region, 0 meaning no limit.
+	CollectorTimeout time.Duration
+	// Concurrency bounds the number of collectors running at once, 0 meaning no limit.
+	Concurrency int
+	// Location is the timezone the report is dated in, UTC if nil.
+	// The collectors of the charges report the month in UTC, the one AWS bills by, and the others the month in this timezone.
+	Location *time.Location
+	// History keeps the metrics of every report sent, if set, and the reports show their changes since then.
+	History history.Store
+	// ChangeWarning and ChangeCritical are the changes in percent above which a metric is marked, 0 meaning never.
+	ChangeWarning  float64
+	ChangeCritical float64
+	// Rules are checked against the metrics, the broken ones being listed as alerts.
+	Rules []rules.Rule
+	// Accounts are watched instead of the account of the credentials, if any.
+	Accounts []Account
+	// Organization discovers more accounts to watch at every run, if set.
+	Organization *Organization
+	// Members keeps the member accounts found by every discovery, if set, so that the accounts that joined
+	// or left are told across runs and restarts.
+	Members history.MemberStore
+	// Routes send the slices of the report of tag values to notifiers of their own, once it was sent.
+	Routes []Route
+	// Log receives the log lines of the job, os.Stdout if nil.
+	Log io.Writer
 }
 
+// TotalTitle is the title of the section adding up the charges of all accounts.
+const TotalTitle = "Consolidated Total"
+
 // SlackJob defines a slack cron job
 type SlackJob struct {
-	regionUsage     map[string]RegionUsage
-	slackWebhookURL string
+	regions    []string
+	sessions   *sessions
+	known      *knownAccounts
+	collectors []stats.Collector
+	notifiers  []notify.Notifier
+	options    Options
+	// running is set while a run is in progress, so that a slow run is not stacked with the next one.
+	running *int32
 }
 
 // NewSlackJob creates a new slack cron job.
-func NewSlackJob(regions []string, webhookURL string) SlackJob {
-	slackJob := SlackJob{
-		regionUsage:     make(map[string]RegionUsage),
-		slackWebhookURL: webhookURL,
-	}
-	for _, region := range regions {
-		sess := session.Must(session.NewSession(&aws.Config{Region: aws.String(region)}))
-		slackJob.regionUsage[region] = RegionUsage{
-			Sess:                              sess,
-			ec2UsageChan:                      make(chan map[string]string),
-			s3UsageChan:                       make(chan map[string]string),
-			cloudFrontUsageChan:               make(chan map[string]string),
-			elasticacheUsageChan:              make(chan map[string]string),
-			rdsUsageChan:                      make(chan map[string]string),
-			billingEstimationCurrentMonthChan: make(chan []float64),
-			billingEstimationLastMonthChan:    make(chan []float64),
-		}
+// The report is delivered to every given notifier.
+func NewSlackJob(regions []string, collectors []stats.Collector, notifiers []notify.Notifier, options Options) SlackJob {
+	return SlackJob{
+		regions:    regions,
+		sessions:   newSessions(regions),
+		known:      &knownAccounts{store: options.Members, job: options.Name},
+		collectors: collectors,
+		notifiers:  notifiers,
+		options:    options,
+		running:    new(int32),
 	}
-	return slackJob
 }
 
-func getSlackAttachmentFields(m map[string]string) []SlackAttachmentField {
-	fields := make([]SlackAttachmentField, 0)
-	keys := stats.GetSortedKeySlice(m)
-	for _, key := range keys {
-		fields = append(fields, SlackAttachmentField{
-			Title: key,
-			Value: m[key],
-			Short: true,
-		})
-	}
-	return fields
+// collected is what a collector gathered in a region.
+type collected struct {
+	metrics  []report.Metric
+	problems []report.Problem
+	// partial is set if the collector was interrupted by a timeout.
+	partial bool
 }
 
-// Run runs the slack cron job.
-func (o SlackJob) Run() {
-	// Get EC2 usage for current session
-
-	ec2UsageMap := make(map[string]map[string]string)
-	s3UsageMap := make(map[string]map[string]string)
-	cloudFrontUsageMap := make(map[string]map[string]string)
-	rdsUsageMap := make(map[string]map[string]string)
-	elasticacheUsageMap := make(map[string]map[string]string)
-	billingEstimationCurrentMonth := []float64{0, 0}
-	billingEstimationLastMonth := []float64{0, 0}
-
-	parition := endpoints.AwsPartition()
-
-	for region, usage := range o.regionUsage {
-		go func() {
-			usage.ec2UsageChan <- stats.GetEC2Usage(usage.Sess)
-		}()
+// task is a collector to run in a region of an account.
+type task struct {
+	account   int
+	collector int
+	region    string
+	slot      *collected
+}
 
-		now := time.Now().UTC()
-		currentYear, currentMonth, _ := now.Date()
-		currentLocation := now.Location()
-		firstDayOfMonth := time.Date(currentYear, currentMonth, 1, 0, 0, 0, 0, currentLocation)
-		lastDayOfMonth := firstDayOfMonth.AddDate(0, 1, 0).Add(-time.Second)
-		go func() {
-			usage.s3UsageChan <- stats.GetS3Usage(usage.Sess, firstDayOfMonth, lastDayOfMonth)
-		}()
-		go func() {
-			usage.cloudFrontUsageChan <- stats.GetCloudFrontUsage(usage.Sess, firstDayOfMonth, lastDayOfMonth)
-		}()
-		go func() {
-			usage.rdsUsageChan <- stats.GetRDSUsage(usage.Sess, firstDayOfMonth, lastDayOfMonth)
-		}()
-		go func() {
-			usage.elasticacheUsageChan <- stats.GetElasticacheUsage(usage.Sess, firstDayOfMonth, lastDayOfMonth)
-		}()
-		go func() {
-			// fmt.Println("Gather accumulated estimated billing for this month", firstDayOfMonth, lastDayOfMonth)
-			month, average := stats.GetEstimatedBilling(usage.Sess, firstDayOfMonth, lastDayOfMonth)
-			usage.billingEstimationCurrentMonthChan <- []float64{month, average}
-		}()
+// collect runs the collectors across all accounts and regions with a bounded pool of workers
+// and assembles their metrics and problems into a report of the billing period, in account, collector then region order.
+// The collectors of the billing month are run over the billing period, and the others over the local one.
+// With several accounts, the report ends with the total of their charges.
+func (o SlackJob) collect(ctx context.Context, billing, local report.Period) report.Report {
+	accounts, events, problems := o.accounts(ctx)
+	results := make([][][]collected, len(accounts))
+	tasks := make([]task, 0, len(accounts)*len(o.collectors)*len(o.regions))
+	for a := range accounts {
+		results[a] = make([][]collected, len(o.collectors))
+		for i, collector := range o.collectors {
+			if collector.Scope() == stats.GlobalScope {
+				results[a][i] = make([]collected, 1)
+				tasks = append(tasks, task{account: a, collector: i, region: stats.GlobalRegion, slot: &results[a][i][0]})
+				continue
+			}
+			results[a][i] = make([]collected, len(o.regions))
+			for j, region := range o.regions {
+				tasks = append(tasks, task{account: a, collector: i, region: region, slot: &results[a][i][j]})
+			}
+		}
+	}
 
-		firstDayOfLastMonth := firstDayOfMonth.AddDate(0, -1, 0)
-		lastDayOfLastMonth := firstDayOfLastMonth.AddDate(0, 1, 0).Add(-time.Second)
+	workers := o.options.Concurrency
+	if workers <= 0 || workers > len(tasks) {
+		workers = len(tasks)
+	}
+	queue := make(chan task)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
 		go func() {
-			// fmt.Println("Gather accumulated estimated billing for last month", firstDayOfLastMonth, lastDayOfLastMonth)
-			month, average := stats.GetEstimatedBilling(usage.Sess, firstDayOfLastMonth, lastDayOfLastMonth)
-			usage.billingEstimationLastMonthChan <- []float64{month, average}
+			defer wg.Done()
+			for t := range queue {
+				account, collector := accounts[t.account], o.collectors[t.collector]
+				period := local
+				if collector.BillingMonth() {
+					period = billing
+				}
+				*t.slot = o.runCollector(ctx, collector, account.Name, t.region, o.sessions.of(account, t.region), period)
+			}
 		}()
-
-		ec2UsageMap[region] = <-usage.ec2UsageChan
-		s3UsageMap[region] = <-usage.s3UsageChan
-		cloudFrontUsageMap[region] = <-usage.cloudFrontUsageChan
-		rdsUsageMap[region] = <-usage.rdsUsageChan
-		elasticacheUsageMap[region] = <-usage.elasticacheUsageChan
-
-		billingEstimation := <-usage.billingEstimationCurrentMonthChan
-		billingEstimationCurrentMonth[0] += billingEstimation[0]
-		billingEstimationCurrentMonth[1] += billingEstimation[1]
-
-		billingEstimation = <-usage.billingEstimationLastMonthChan
-		billingEstimationLastMonth[0] += billingEstimation[0]
-		billingEstimationLastMonth[1] += billingEstimation[1]
 	}
+	for _, t := range tasks {
+		queue <- t
+	}
+	close(queue)
+	wg.Wait()
 
-	slackAttachments := make([]SlackAttachment, 0)
-
-	// Add ec2 usage
-	ec2UsageAttachment := SlackAttachment{
-		Fallback: "EC2 Usage",
-		PreText:  "EC2 Usage",
-		Color:    "#D00000",
-		Fields:   make([]SlackAttachmentField, 0),
+	r := report.Report{
+		Title:         o.options.Title,
+		Period:        billing,
+		GeneratedAt:   o.now(),
+		AccountEvents: events,
+		Sections:      make([]report.Section, 0, len(accounts)*len(o.collectors)+1),
+		Problems:      append(make([]report.Problem, 0), problems...),
 	}
-	for region, ec2Usage := range ec2UsageMap {
-		if len(ec2Usage) == 0 {
-			continue
+	for a, account := range accounts {
+		for i, collector := range o.collectors {
+			section := report.Section{
+				Account: account.Name,
+				Service: collector.Name(),
+				Title:   collector.Title(),
+				Global:  collector.Scope() == stats.GlobalScope,
+				Metrics: make([]report.Metric, 0),
+			}
+			for _, result := range results[a][i] {
+				section.Metrics = append(section.Metrics, result.metrics...)
+				section.Partial = section.Partial || result.partial
+				section.Failed = section.Failed || len(result.problems) > 0
+				r.Problems = append(r.Problems, result.problems...)
+			}
+			r.Sections = append(r.Sections, section)
 		}
-		paritionRegion := parition.Regions()[region]
-		ec2UsageAttachment.Fields = append(ec2UsageAttachment.Fields, SlackAttachmentField{
-			Title: "",
-			Value: fmt.Sprintf("_&lt;%s: %s&gt;_", paritionRegion.Description(), region),
-			Short: false,
-		})
-		ec2UsageAttachment.Fields = append(ec2UsageAttachment.Fields, getSlackAttachmentFields(ec2Usage)...)
 	}
-	slackAttachments = append(slackAttachments, ec2UsageAttachment)
+	if len(accounts) > 1 {
+		management := make(map[string]bool)
+		for _, account := range accounts {
+			if account.Management {
+				management[account.Name] = true
+			}
+		}
+		r.Sections = append(r.Sections, total(r.Sections, management))
+	}
+	return r
+}
 
-	// Add s3 usage
-	s3UsageAttachment := SlackAttachment{
-		Fallback: "S3 Usage",
-		PreText:  "S3 Usage",
-		Color:    "#D00000",
-		Fields:   make([]SlackAttachmentField, 0),
+// total adds up the charges of the sections across accounts and regions,
+// by service, metric and label, in the order they first appear.
+// The management accounts of given names are left out, as their charges are already those of the whole organization.
+// A change is added up only if all the charges added up have one since the same time.
+func total(sections []report.Section, management map[string]bool) report.Section {
+	section := report.Section{
+		Service: "total",
+		Title:   TotalTitle,
+		Global:  true,
+		Metrics: make([]report.Metric, 0),
 	}
-	for region, s3Usage := range s3UsageMap {
-		if len(s3Usage) == 0 {
+	index := make(map[string]int)
+	counts := make([]int, 0)
+	for _, s := range sections {
+		if management[s.Account] {
 			continue
 		}
-		paritionRegion := parition.Regions()[region]
-		s3UsageAttachment.Fields = append(s3UsageAttachment.Fields, SlackAttachmentField{
-			Title: "",
-			Value: fmt.Sprintf("_&lt;%s: %s&gt;_", paritionRegion.Description(), region),
-			Short: false,
-		})
-		s3UsageAttachment.Fields = append(s3UsageAttachment.Fields, getSlackAttachmentFields(s3Usage)...)
+		section.Partial = section.Partial || s.Partial || s.Failed
+		for _, metric := range s.Metrics {
+			if metric.Unit != report.USD {
+				continue
+			}
+			key := metric.Service + "|" + metric.Name + "|" + metric.Label()
+			i, ok := index[key]
+			if !ok {
+				i = len(section.Metrics)
+				index[key] = i
+				counts = append(counts, 0)
+				section.Metrics = append(section.Metrics, report.Metric{
+					Service:    metric.Service,
+					Name:       metric.Name,
+					Unit:       metric.Unit,
+					Dimensions: metric.Dimensions,
+					Period:     metric.Period,
+					ToDate:     metric.ToDate,
+					Changes:    append([]report.Change{}, metric.Changes...),
+				})
+				if metric.Band != nil {
+					section.Metrics[i].Band = &report.Band{}
+				}
+			}
+			summed := &section.Metrics[i]
+			counts[i]++
+			summed.Value += metric.Value
+			if summed.Band != nil && metric.Band != nil {
+				summed.Band.Low += metric.Band.Low
+				summed.Band.High += metric.Band.High
+			} else {
+				summed.Band = nil
+			}
+			if counts[i] > 1 {
+				summed.Changes = addChanges(summed.Changes, metric.Changes)
+			}
+		}
 	}
-	slackAttachments = append(slackAttachments, s3UsageAttachment)
+	return section
+}
 
-	// Add cloudfront usage
-	cloudFrontUsageAttachment := SlackAttachment{
-		Fallback: "CloudFront Usage",
-		PreText:  "CloudFront Usage",
-		Color:    "#D00000",
-		Fields:   make([]SlackAttachmentField, 0),
-	}
-	for region, cloudFrontUsage := range cloudFrontUsageMap {
-		if len(cloudFrontUsage) == 0 {
-			continue
+// addChanges adds the values of the changes since the same time, dropping those that aren't in both.
+func addChanges(changes, others []report.Change) []report.Change {
+	added := make([]report.Change, 0, len(changes))
+	for _, change := range changes {
+		for _, other := range others {
+			if other.Since == change.Since {
+				change.Value += other.Value
+				added = append(added, change)
+				break
+			}
 		}
-		paritionRegion := parition.Regions()[region]
-		cloudFrontUsageAttachment.Fields = append(cloudFrontUsageAttachment.Fields, SlackAttachmentField{
-			Title: "",
-			Value: fmt.Sprintf("_&lt;%s: %s&gt;_", paritionRegion.Description(), region),
-			Short: false,
-		})
-		cloudFrontUsageAttachment.Fields = append(cloudFrontUsageAttachment.Fields, getSlackAttachmentFields(cloudFrontUsage)...)
 	}
-	slackAttachments = append(slackAttachments, cloudFrontUsageAttachment)
+	return added
+}
 
-	// Add RDS usage
-	rdsUsageAttachment := SlackAttachment{
-		Fallback: "RDS Usage",
-		PreText:  "RDS Usage",
-		Color:    "#D00000",
-		Fields:   make([]SlackAttachmentField, 0),
+// runCollector runs a collector in a region of an account within the collector timeout
+// and fills in where its metrics and problems come from.
+func (o SlackJob) runCollector(ctx context.Context, collector stats.Collector, account, region string, sess *session.Session, period report.Period) collected {
+	if o.options.CollectorTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, o.options.CollectorTimeout)
+		defer cancel()
 	}
-	for region, rdsUsage := range rdsUsageMap {
-		if len(rdsUsage) == 0 {
-			continue
+	metrics, err := collector.Collect(ctx, sess, period)
+	for i := range metrics {
+		metrics[i].Account = account
+		metrics[i].Service = collector.Name()
+		metrics[i].Region = region
+		metrics[i].ToDate = collector.ToDate()
+		if metrics[i].Period.Start.IsZero() {
+			metrics[i].Period = period
 		}
-		paritionRegion := parition.Regions()[region]
-		rdsUsageAttachment.Fields = append(rdsUsageAttachment.Fields, SlackAttachmentField{
-			Title: "",
-			Value: fmt.Sprintf("_&lt;%s: %s&gt;_", paritionRegion.Description(), region),
-			Short: false,
+	}
+	problems := problemsOf(err, account, collector.Name(), region)
+	partial := ctx.Err() != nil
+	for _, problem := range problems {
+		o.logf("Failed to collect usage: %s\n", format.Problem(problem))
+		partial = partial || problem.Code == stats.CodeTimeout
+	}
+	return collected{metrics: metrics, problems: problems, partial: partial}
+}
+
+// problemsOf turns the error returned by a collector into the problems of the report.
+func problemsOf(err error, account, service, region string) []report.Problem {
+	if err == nil {
+		return nil
+	}
+	apiErrors, ok := err.(stats.Errors)
+	if !ok {
+		apiErrors = stats.Errors{{Err: err}}
+	}
+	problems := make([]report.Problem, 0, len(apiErrors))
+	for _, apiError := range apiErrors {
+		problems = append(problems, report.Problem{
+			Account: account,
+			Service: service,
+			Region:  region,
+			API:     apiError.API,
+			Code:    apiError.Code(),
+			Message: apiError.Message(),
 		})
-		rdsUsageAttachment.Fields = append(rdsUsageAttachment.Fields, getSlackAttachmentFields(rdsUsage)...)
 	}
+	return problems
+}
 
-	slackAttachments = append(slackAttachments, rdsUsageAttachment)
+// Report collects the report of the current billing month, and of the current month in the job's timezone, within the run timeout,
+// compares it with the history if any, marks the large changes and checks it against the rules.
+func (o SlackJob) Report() report.Report {
+	ctx := context.Background()
+	if o.options.RunTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, o.options.RunTimeout)
+		defer cancel()
+	}
+	now := o.now()
+	r := o.collect(ctx, report.MonthOf(now.UTC()), report.MonthOf(now))
+	if o.options.History != nil {
+		if err := history.Compare(o.options.History, &r); err != nil {
+			o.logf("Failed to compare report with history: %s\n", err.Error())
+		}
+	}
+	o.markChanges(&r)
+	rules.Evaluate(o.options.Rules, &r)
+	return r
+}
 
-	// Add ElastiCache usage
-	elasticacheUsageAttachment := SlackAttachment{
-		Fallback: "Elasticache Usage",
-		PreText:  "Elasticache Usage",
-		Color:    "#D00000",
-		Fields:   make([]SlackAttachmentField, 0),
+// markChanges raises the severity of the metrics that changed more than the thresholds in percent.
+func (o SlackJob) markChanges(r *report.Report) {
+	for i := range r.Sections {
+		for j := range r.Sections[i].Metrics {
+			metric := &r.Sections[i].Metrics[j]
+			for _, change := range metric.Changes {
+				severity := report.Normal
+				percent := metric.PercentChange(change)
+				if o.options.ChangeCritical > 0 && percent > o.options.ChangeCritical {
+					severity = report.Critical
+				} else if o.options.ChangeWarning > 0 && percent > o.options.ChangeWarning {
+					severity = report.Warning
+				}
+				if severity.Above(metric.Severity) {
+					metric.Severity = severity
+				}
+			}
+		}
 	}
-	for region, elasticacheUsage := range elasticacheUsageMap {
-		if len(elasticacheUsage) == 0 {
+}
+
+// Send delivers a report to every notifier and returns an error if any of them failed.
+// Every failure is logged per destination, which is all the cron runs report of them.
+// The delivery gets a run timeout of its own, so that a slow destination doesn't hold the next runs back.
+func (o SlackJob) Send(r report.Report) error {
+	o.logf("Sending report to %d destination(s)\n", len(o.notifiers))
+	ctx, cancel := o.sendContext()
+	defer cancel()
+	failed := 0
+	for _, result := range notify.Fanout(ctx, o.notifiers, r) {
+		if result.Err != nil {
+			o.logf("Failed to send report to %s: %s\n", result.Destination, result.Err.Error())
+			failed++
 			continue
 		}
-		paritionRegion := parition.Regions()[region]
-		elasticacheUsageAttachment.Fields = append(elasticacheUsageAttachment.Fields, SlackAttachmentField{
-			Title: "",
-			Value: fmt.Sprintf("_&lt;%s: %s&gt;_", paritionRegion.Description(), region),
-			Short: false,
-		})
-		elasticacheUsageAttachment.Fields = append(elasticacheUsageAttachment.Fields, getSlackAttachmentFields(elasticacheUsage)...)
+		o.logf("Sent report to %s in %s\n", result.Destination, result.Duration)
 	}
-	slackAttachments = append(slackAttachments, elasticacheUsageAttachment)
+	if failed > 0 {
+		return fmt.Errorf("failed to send report to %d of %d destination(s)", failed, len(o.notifiers))
+	}
+	return nil
+}
 
-	// Add cost estimation
-	billingEstimationAttachment := SlackAttachment{
-		Fallback: "Estimated Billing",
-		PreText:  "Estimated Billing",
-		Color:    "#D00000",
-		Fields:   make([]SlackAttachmentField, 0),
+// sendContext returns the context bounding the delivery of a report by the run timeout.
+func (o SlackJob) sendContext() (context.Context, context.CancelFunc) {
+	if o.options.RunTimeout > 0 {
+		return context.WithTimeout(context.Background(), o.options.RunTimeout)
 	}
-	billingEstimationAttachment.Fields = append(billingEstimationAttachment.Fields, SlackAttachmentField{
-		Title: "Daily Average This Month",
-		Value: fmt.Sprintf("$%.02f USD", billingEstimationCurrentMonth[1]),
-		Short: true,
-	})
-	billingEstimationAttachment.Fields = append(billingEstimationAttachment.Fields, SlackAttachmentField{
-		Title: "Accumulated This Month",
-		Value: fmt.Sprintf("$%.02f USD", billingEstimationCurrentMonth[0]),
-		Short: true,
-	})
-	billingEstimationAttachment.Fields = append(billingEstimationAttachment.Fields, SlackAttachmentField{
-		Title: "Daily Average Last Month",
-		Value: fmt.Sprintf("$%.02f USD", billingEstimationLastMonth[1]),
-		Short: true,
-	})
-	billingEstimationAttachment.Fields = append(billingEstimationAttachment.Fields, SlackAttachmentField{
-		Title: "Accumulated Last Month",
-		Value: fmt.Sprintf("$%.02f USD", billingEstimationLastMonth[0]),
-		Short: true,
-	})
-	slackAttachments = append(slackAttachments, billingEstimationAttachment)
+	return context.WithCancel(context.Background())
+}
 
-	slackAttachmentsBytes, _ := json.Marshal(SlackAttachments{Attacments: slackAttachments})
-	// fmt.Println(string(slackAttachmentsBytes))
+// now returns the current time in the job's timezone.
+func (o SlackJob) now() time.Time {
+	if o.options.Location == nil {
+		return time.Now().UTC()
+	}
+	return time.Now().In(o.options.Location)
+}
 
-	// call slack webhook URL.
-	fmt.Println("Sending report to slack channel")
-	payload := strings.NewReader(string(slackAttachmentsBytes))
-	req, _ := http.NewRequest("POST", o.slackWebhookURL, payload)
-	req.Header.Add("content-type", "application/json")
-	req.Header.Add("cache-control", "no-cache")
-	res, _ := http.DefaultClient.Do(req)
-	body, _ := ioutil.ReadAll(res.Body)
-	res.Body.Close()
-	fmt.Println(string(body))
+// logf prints a log line, prefixed by the job name if any.
+func (o SlackJob) logf(msg string, args ...interface{}) {
+	if o.options.Name != "" {
+		msg = "[" + o.options.Name + "] " + msg
+	}
+	log := o.options.Log
+	if log == nil {
+		log = os.Stdout
+	}
+	fmt.Fprintf(log, msg, args...)
 }
 
-// SlackAttachmentField defines a slack attachment field
-type SlackAttachmentField struct {
-	Title string `json:"title"`
-	Value string `json:"value"`
-	Short bool   `json:"short"`
+// RunOnce collects the report, keeps it in the history, sends it and its slices to the routes.
+// It returns an error if any destination failed, a report that couldn't be kept is only logged.
+func (o SlackJob) RunOnce() error {
+	r := o.Report()
+	if o.options.History != nil {
+		if err := o.options.History.Append(history.Records(r)); err != nil {
+			o.logf("Failed to keep report in history: %s\n", err.Error())
+		}
+	}
+	err := o.Send(r)
+	if failed := o.sendRoutes(r); failed > 0 && err == nil {
+		err = fmt.Errorf("failed to send the slices of the report to %d destination(s)", failed)
+	}
+	return err
 }
 
-// SlackAttachment defines a slack attachment
-type SlackAttachment struct {
-	Fallback   string                 `json:"fallback,omitempty"`
-	Color      string                 `json:"color,omitempty"`
-	PreText    string                 `json:"pretext,omitempty"`
-	AuthorName string                 `json:"author_name,omitempty"`
-	AuthorLink string                 `json:"author_link,omitempty"`
-	AuthorIcon string                 `json:"author_icon,omitempty"`
-	Title      string                 `json:"title,omitempty"`
-	TitleLink  string                 `json:"title_link,omitempty"`
-	Text       string                 `json:"text,omitempty"`
-	Fields     []SlackAttachmentField `json:"fields,omitempty"`
-	ImageURL   string                 `json:"image_url,omitempty"`
-	ThumbURL   string                 `json:"thumb_url,omitempty"`
-	Footer     string                 `json:"footer,omitempty"`
-	FooterIcon string                 `json:"footer_icon,omitempty"`
-	TS         int64                  `json:"ts,omitempty"`
+// Run runs the slack cron job, unless the previous run is still in progress.
+func (o SlackJob) Run() {
+	o.exclusive(func() { o.RunOnce() })
 }
 
-// SlackAttachments defines slack attachments
-type SlackAttachments struct {
-	Attacments []SlackAttachment `json:"attachments"`
+// exclusive runs fn unless the previous run is still in progress.
+func (o SlackJob) exclusive(fn func()) {
+	if !atomic.CompareAndSwapInt32(o.running, 0, 1) {
+		o.logf("Skipping report, the previous run is still in progress\n")
+		return
+	}
+	defer atomic.StoreInt32(o.running, 0)
+	fn()
 }
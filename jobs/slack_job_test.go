@@ -0,0 +1,248 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/WUMUXIAN/aws-slack-bot/notify"
+	"github.com/WUMUXIAN/aws-slack-bot/render"
+	"github.com/WUMUXIAN/aws-slack-bot/report"
+	"github.com/WUMUXIAN/aws-slack-bot/stats"
+	"github.com/aws/aws-sdk-go/aws/session"
+)
+
+func TestMarkChanges(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    float64
+		changes  []float64
+		severity report.Severity
+		warning  float64
+		critical float64
+		want     report.Severity
+	}{
+		{"no change", 100, nil, report.Normal, 20, 50, report.Normal},
+		{"small change", 110, []float64{100}, report.Normal, 20, 50, report.Normal},
+		{"warning rise", 130, []float64{100}, report.Normal, 20, 50, report.Warning},
+		{"warning drop", 70, []float64{100}, report.Normal, 20, 50, report.Warning},
+		{"critical", 160, []float64{100}, report.Normal, 20, 50, report.Critical},
+		{"from zero", 1, []float64{0}, report.Normal, 20, 50, report.Critical},
+		{"largest change", 130, []float64{125, 60}, report.Normal, 20, 50, report.Critical},
+		{"never lowered", 100, []float64{100}, report.Critical, 20, 50, report.Critical},
+		{"no thresholds", 1000, []float64{100}, report.Normal, 0, 0, report.Normal},
+		{"warning only", 1000, []float64{100}, report.Normal, 20, 0, report.Warning},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			metric := report.Metric{Name: "Charges", Value: test.value, Severity: test.severity}
+			for _, value := range test.changes {
+				metric.Changes = append(metric.Changes, report.Change{Since: report.SincePrevious, Value: value})
+			}
+			r := report.Report{Sections: []report.Section{{Service: "billing", Metrics: []report.Metric{metric}}}}
+			job := SlackJob{options: Options{ChangeWarning: test.warning, ChangeCritical: test.critical}}
+			job.markChanges(&r)
+			if got := r.Sections[0].Metrics[0].Severity; got != test.want {
+				t.Errorf("got severity %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestTotal(t *testing.T) {
+	changes := func(previous, lastWeek float64) []report.Change {
+		return []report.Change{{Since: report.SincePrevious, Value: previous}, {Since: report.SinceLastWeek, Value: lastWeek}}
+	}
+	sections := []report.Section{
+		{Service: "billing", Account: "production", Global: true, Metrics: []report.Metric{
+			{Service: "billing", Account: "production", Name: "Accumulated This Month", Value: 100, Unit: report.USD, Changes: changes(90, 70)},
+			{Service: "billing", Account: "production", Name: "Forecast", Value: 300, Unit: report.USD, Band: &report.Band{Low: 250, High: 350}},
+		}},
+		{Service: "ec2", Account: "production", Metrics: []report.Metric{
+			{Service: "ec2", Account: "production", Region: "eu-west-1", Name: "Running Instances", Value: 4, Unit: report.Count},
+		}},
+		{Service: "billing", Account: "staging", Global: true, Partial: true, Metrics: []report.Metric{
+			{Service: "billing", Account: "staging", Name: "Accumulated This Month", Value: 50, Unit: report.USD, Changes: changes(40, 30)[:1]},
+			{Service: "billing", Account: "staging", Name: "Forecast", Value: 100, Unit: report.USD},
+		}},
+	}
+	section := total(sections, nil)
+
+	if !section.Partial {
+		t.Error("got a complete total, want it partial as a section added up is")
+	}
+	if len(section.Metrics) != 2 {
+		t.Fatalf("got %d metrics %v, want the 2 charges", len(section.Metrics), section.Metrics)
+	}
+	accumulated := section.Metrics[0]
+	if accumulated.Name != "Accumulated This Month" || accumulated.Value != 150 || accumulated.Account != "" {
+		t.Errorf("got %+v, want 150 accumulated this month in no account", accumulated)
+	}
+	// Only the change both accounts have is added up.
+	if len(accumulated.Changes) != 1 || accumulated.Changes[0].Since != report.SincePrevious || accumulated.Changes[0].Value != 130 {
+		t.Errorf("got changes %+v, want 130 since the previous report", accumulated.Changes)
+	}
+	// The band is dropped as an account has none.
+	forecast := section.Metrics[1]
+	if forecast.Value != 400 || forecast.Band != nil {
+		t.Errorf("got forecast %f with band %v, want 400 without a band", forecast.Value, forecast.Band)
+	}
+	// The first account's changes are not changed by adding the others up.
+	if value := sections[0].Metrics[0].Changes[0].Value; value != 90 {
+		t.Errorf("got the production change changed to %f, want 90", value)
+	}
+}
+
+func TestTotalManagement(t *testing.T) {
+	charges := func(account string, value float64) report.Section {
+		return report.Section{Service: "billing", Account: account, Global: true, Metrics: []report.Metric{
+			{Service: "billing", Account: account, Name: "Accumulated This Month", Value: value, Unit: report.USD},
+		}}
+	}
+	// The charges of the management account are those of the whole organization, its own and the members'.
+	sections := []report.Section{charges("management", 160), charges("production", 100), charges("staging", 50)}
+	sections[0].Failed = true
+
+	section := total(sections, map[string]bool{"management": true})
+	if len(section.Metrics) != 1 || section.Metrics[0].Value != 150 {
+		t.Errorf("got %v, want the 150 charges of the members only", section.Metrics)
+	}
+	if section.Partial {
+		t.Error("got a partial total, want it complete as the failed management account isn't added up")
+	}
+}
+
+func TestAddChanges(t *testing.T) {
+	at := time.Date(2026, 10, 16, 8, 0, 0, 0, time.UTC)
+	changes := []report.Change{{Since: report.SincePrevious, Value: 10, At: at}, {Since: report.SinceLastWeek, Value: 5, At: at}}
+	tests := []struct {
+		name   string
+		others []report.Change
+		want   []report.Change
+	}{
+		{"both", []report.Change{{Since: report.SinceLastWeek, Value: 2}, {Since: report.SincePrevious, Value: 1}},
+			[]report.Change{{Since: report.SincePrevious, Value: 11, At: at}, {Since: report.SinceLastWeek, Value: 7, At: at}}},
+		{"one", []report.Change{{Since: report.SinceLastWeek, Value: 2}}, []report.Change{{Since: report.SinceLastWeek, Value: 7, At: at}}},
+		{"none", nil, []report.Change{}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			added := addChanges(changes, test.others)
+			if !reflect.DeepEqual(added, test.want) {
+				t.Errorf("got %+v, want %+v", added, test.want)
+			}
+		})
+	}
+}
+
+// sendJob returns a job delivering to a webhook to the URL with the default delivery policy.
+func sendJob(url string, runTimeout time.Duration) SlackJob {
+	webhook := notify.NewWebhook("test", url, render.JSON{})
+	webhook.Log = ioutil.Discard
+	return NewSlackJob(nil, nil, []notify.Notifier{webhook}, Options{RunTimeout: runTimeout, Log: ioutil.Discard})
+}
+
+func TestSendRetryAfterPastMaxBackoff(t *testing.T) {
+	posts := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		posts++
+		w.Header().Set("Retry-After", "7200")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+
+	start := time.Now()
+	err := sendJob(server.URL, 0).Send(report.Report{})
+	if err == nil || !strings.Contains(err.Error(), "1 of 1") {
+		t.Errorf("got error %v, want the destination to fail", err)
+	}
+	if time.Since(start) > 5*time.Second {
+		t.Errorf("gave up after %s, want right away", time.Since(start))
+	}
+	if posts != 1 {
+		t.Errorf("got %d posts, want 1", posts)
+	}
+}
+
+func TestSendRunTimeout(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer server.Close()
+	defer close(release)
+
+	start := time.Now()
+	if err := sendJob(server.URL, 100*time.Millisecond).Send(report.Report{}); err == nil {
+		t.Error("got no error, want the hanging destination to fail")
+	}
+	if time.Since(start) > 5*time.Second {
+		t.Errorf("gave up after %s, want the run timeout", time.Since(start))
+	}
+}
+
+// failingCollector is a global collector whose API calls are denied.
+type failingCollector struct{}
+
+func (failingCollector) Name() string       { return "failing" }
+func (failingCollector) Title() string      { return "Failing" }
+func (failingCollector) Scope() stats.Scope { return stats.GlobalScope }
+func (failingCollector) ToDate() bool       { return false }
+func (failingCollector) BillingMonth() bool { return true }
+func (failingCollector) Collect(ctx context.Context, sess *session.Session, period report.Period) ([]report.Metric, error) {
+	return nil, stats.Errors{{API: "ce:GetCostAndUsage", Err: errors.New("AccessDenied")}}
+}
+
+func TestCollectMarksFailedSections(t *testing.T) {
+	job := NewSlackJob(nil, []stats.Collector{failingCollector{}}, nil, Options{Log: ioutil.Discard})
+	period := report.MonthOf(time.Now().UTC())
+	r := job.collect(context.Background(), period, period)
+	if len(r.Sections) != 1 || !r.Sections[0].Failed {
+		t.Errorf("got sections %+v, want the failing one marked as failed", r.Sections)
+	}
+	if len(r.Problems) != 1 {
+		t.Errorf("got problems %+v, want the denied call", r.Problems)
+	}
+}
+
+// usageCollector is a global collector of a usage, over the billing month or not.
+type usageCollector struct {
+	name    string
+	billing bool
+}
+
+func (o usageCollector) Name() string       { return o.name }
+func (o usageCollector) Title() string      { return o.name }
+func (usageCollector) Scope() stats.Scope   { return stats.GlobalScope }
+func (usageCollector) ToDate() bool         { return true }
+func (o usageCollector) BillingMonth() bool { return o.billing }
+func (usageCollector) Collect(ctx context.Context, sess *session.Session, period report.Period) ([]report.Metric, error) {
+	return []report.Metric{{Name: "Usage", Value: 1, Unit: report.Count}}, nil
+}
+
+func TestCollectPeriods(t *testing.T) {
+	// At 2am on the 1st in Singapore, it is still the last day of the month before in UTC.
+	now := time.Date(2026, 11, 1, 2, 0, 0, 0, time.FixedZone("SGT", 8*60*60))
+	billing, local := report.MonthOf(now.UTC()), report.MonthOf(now)
+	job := NewSlackJob(nil, []stats.Collector{usageCollector{"charges", true}, usageCollector{"instances", false}}, nil, Options{Log: ioutil.Discard})
+	r := job.collect(context.Background(), billing, local)
+
+	if !r.Period.Start.Equal(billing.Start) {
+		t.Errorf("got the report of %s, want the billing month from %s", r.Period.Start, billing.Start)
+	}
+	want := map[string]report.Period{"charges": billing, "instances": local}
+	for _, section := range r.Sections {
+		if len(section.Metrics) != 1 {
+			t.Fatalf("%s: got metrics %v, want 1", section.Service, section.Metrics)
+		}
+		if period := section.Metrics[0].Period; !period.Start.Equal(want[section.Service].Start) || !period.End.Equal(want[section.Service].End) {
+			t.Errorf("%s: got the period from %s, want from %s", section.Service, period.Start, want[section.Service].Start)
+		}
+	}
+}
@@ -1,56 +1,186 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"os"
 	"strings"
 
-	"github.com/WUMUXIAN/aws-slack-bot/jobs"
+	"github.com/WUMUXIAN/aws-slack-bot/render"
 	"github.com/robfig/cron"
 )
 
-func runCronJob() {
-	// Check slack webhook
-	if os.Getenv("SLACK_WEBHOOK_URL") == "" {
-		fmt.Println("Please specify the slack webhook URL")
-		return
-	}
+const usage = `Usage: aws-slack-bot [-config file] [command]
+
+The configuration is read from the YAML or JSON file given by -config or CONFIG_FILE, if any,
+and overridden by the environment variables.
+
+Commands:
+  serve            Send the reports on their schedules (default)
+  run              Send the reports once, exiting with 1 if any destination failed
+  preview          Print the reports to stdout without sending them
+  validate-config  Check the configuration and print a summary of it
+
+The run and preview commands take -schedule to pick a single schedule, preview takes -format.
+`
 
-	// Set regions
-	regions := []string{}
-	if os.Getenv("REGIONS") != "" {
-		regions = strings.Split(os.Getenv("REGIONS"), ",")
+// serve schedules the jobs and holds the process.
+func serve(b bot) error {
+	if err := b.requireDestinations(); err != nil {
+		return err
 	}
-	if len(regions) == 0 {
-		regions = []string{"us-east-1"}
+
+	// Start the cron jobs, each in the timezone of its schedule, and hold the process.
+	for _, s := range b.schedules {
+		runner := cron.NewWithLocation(s.location)
+		err := runner.AddJob(s.cronDefinition, s.newJob())
+		if err != nil {
+			return fmt.Errorf("failed to schedule cron job %s: %s", s.name, err.Error())
+		}
+		runner.Start()
+		defer runner.Stop()
 	}
 
-	// Get cron definition
-	cronDefinition := "0 0 1 * * MON-FRI"
-	if os.Getenv("CRON_DEFINITION") != "" {
-		cronDefinition = os.Getenv("CRON_DEFINITION")
+	holder := make(chan int)
+	<-holder
+	return nil
+}
+
+// run sends the reports once, one schedule after the other.
+func run(b bot, args []string) error {
+	flags := flag.NewFlagSet("run", flag.ExitOnError)
+	scheduleName := flags.String("schedule", "", "the schedule to run, all of them by default")
+	flags.Parse(args)
+
+	if err := b.requireDestinations(); err != nil {
+		return err
+	}
+	schedules, err := b.selectSchedules(*scheduleName)
+	if err != nil {
+		return err
+	}
+	failed := make([]string, 0)
+	for _, s := range schedules {
+		if err := s.newJob().RunOnce(); err != nil {
+			failed = append(failed, s.name)
+		}
 	}
+	if len(failed) > 0 {
+		return fmt.Errorf("failed to send the report of %s", strings.Join(failed, ", "))
+	}
+	return nil
+}
 
-	// Start the cron jobs and hold the process.
-	cron := cron.New()
-	// Job for the us region
-	slackJob := jobs.NewSlackJob(
-		regions,
-		os.Getenv("SLACK_WEBHOOK_URL"),
-	)
-	err := cron.AddJob(cronDefinition, slackJob)
+// preview prints the reports in given format, split into messages as they would be sent.
+func preview(b bot, args []string) error {
+	flags := flag.NewFlagSet("preview", flag.ExitOnError)
+	formatName := flags.String("format", "markdown", "the format of the report, one of "+strings.Join(render.Names(), ", "))
+	scheduleName := flags.String("schedule", "", "the schedule to preview, all of them by default")
+	flags.Parse(args)
+
+	renderer, err := render.New(*formatName)
 	if err != nil {
-		fmt.Println("Failed to schedule cron job:", err.Error())
-		return
+		return err
 	}
+	schedules, err := b.selectSchedules(*scheduleName)
+	if err != nil {
+		return err
+	}
+	for _, s := range schedules {
+		// Keep the collection logs out of the printed report.
+		fmt.Fprintf(os.Stderr, "Previewing the report of %s\n", s.name)
+		s.options.Log = os.Stderr
+		// A preview doesn't take the accounts that joined or left from the next report.
+		s.options.Members = nil
+		r := s.newSlackJob().Report()
 
-	cron.Start()
-	defer cron.Stop()
+		messages, err := render.Messages(renderer, r)
+		if err != nil {
+			return fmt.Errorf("failed to render report: %s", err.Error())
+		}
+		for _, message := range messages {
+			fmt.Println(string(message))
+		}
+	}
+	return nil
+}
 
-	holder := make(chan int)
-	<-holder
+// validateConfig prints a summary of a valid configuration.
+func validateConfig(b bot) error {
+	if err := b.requireDestinations(); err != nil {
+		return err
+	}
+	for _, s := range b.schedules {
+		names := make([]string, 0, len(s.collectors))
+		for _, collector := range s.collectors {
+			names = append(names, collector.Name())
+		}
+		destinations := make([]string, 0, len(s.notifiers))
+		for _, notifier := range s.notifiers {
+			destinations = append(destinations, notifier.Name())
+		}
+		fmt.Printf("Schedule %s: %s (%s)\n", s.name, s.cronDefinition, s.location)
+		if len(s.options.Accounts) > 0 || s.options.Organization != nil {
+			accounts := make([]string, 0, len(s.options.Accounts)+1)
+			for _, account := range s.options.Accounts {
+				accounts = append(accounts, account.Name)
+			}
+			if s.options.Organization != nil {
+				accounts = append(accounts, "the members of the organization")
+			}
+			fmt.Println("  Accounts:", strings.Join(accounts, ", "))
+		}
+		fmt.Println("  Regions:", strings.Join(s.regions, ", "))
+		fmt.Println("  Collectors:", strings.Join(names, ", "))
+		fmt.Println("  Destinations:", strings.Join(destinations, ", "))
+	}
+	if len(b.schedules) > 0 {
+		for _, route := range b.schedules[0].options.Routes {
+			destinations := make([]string, 0, len(route.Notifiers))
+			for _, notifier := range route.Notifiers {
+				destinations = append(destinations, notifier.Name())
+			}
+			fmt.Printf("Route %s %s: %s\n", route.Tag, route.Value, strings.Join(destinations, ", "))
+		}
+	}
+	fmt.Println("Configuration is valid")
+	return nil
 }
 
 func main() {
-	runCronJob()
+	flag.Usage = func() { fmt.Fprint(os.Stderr, usage) }
+	configFile := flag.String("config", os.Getenv("CONFIG_FILE"), "the configuration file")
+	flag.Parse()
+
+	command, args := "serve", []string{}
+	if flag.NArg() > 0 {
+		command, args = flag.Arg(0), flag.Args()[1:]
+	}
+	switch command {
+	case "serve", "run", "preview", "validate-config":
+	case "help":
+		fmt.Print(usage)
+		return
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown command %q\n\n%s", command, usage)
+		os.Exit(2)
+	}
+
+	b, err := loadBot(*configFile)
+	if err == nil {
+		switch command {
+		case "serve":
+			err = serve(b)
+		case "run":
+			err = run(b, args)
+		case "preview":
+			err = preview(b, args)
+		case "validate-config":
+			err = validateConfig(b)
+		}
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err.Error())
+		os.Exit(1)
+	}
 }
@@ -0,0 +1,70 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net"
+	"net/smtp"
+	"strings"
+	"time"
+
+	"github.com/WUMUXIAN/aws-slack-bot/render"
+	"github.com/WUMUXIAN/aws-slack-bot/report"
+)
+
+// Email sends reports by email through an SMTP server.
+type Email struct {
+	Label    string
+	Addr     string
+	Username string
+	Password string
+	From     string
+	To       []string
+	Renderer render.Renderer
+}
+
+// NewEmail creates an email notifier sending reports rendered by given renderer through the SMTP server at addr.
+// The SMTP server is authenticated against only if username is set.
+func NewEmail(label, addr, username, password, from string, to []string, renderer render.Renderer) Email {
+	return Email{
+		Label:    label,
+		Addr:     addr,
+		Username: username,
+		Password: password,
+		From:     from,
+		To:       to,
+		Renderer: renderer,
+	}
+}
+
+// Name implements Notifier.
+func (o Email) Name() string { return o.Label }
+
+// Notify sends the report as the body of a single email.
+func (o Email) Notify(ctx context.Context, r report.Report) error {
+	body, err := o.Renderer.Render(r)
+	if err != nil {
+		return fmt.Errorf("failed to render report: %s", err.Error())
+	}
+
+	var message bytes.Buffer
+	fmt.Fprintf(&message, "From: %s\r\n", o.From)
+	fmt.Fprintf(&message, "To: %s\r\n", strings.Join(o.To, ", "))
+	fmt.Fprintf(&message, "Subject: %s %s\r\n", r.Heading(), r.GeneratedAt.Format("2006-01-02"))
+	fmt.Fprintf(&message, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	fmt.Fprintf(&message, "MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&message, "Content-Type: %s\r\n", o.Renderer.ContentType())
+	fmt.Fprintf(&message, "\r\n")
+	message.Write(body)
+
+	var auth smtp.Auth
+	if o.Username != "" {
+		host, _, err := net.SplitHostPort(o.Addr)
+		if err != nil {
+			return err
+		}
+		auth = smtp.PlainAuth("", o.Username, o.Password, host)
+	}
+	return smtp.SendMail(o.Addr, auth, o.From, o.To, message.Bytes())
+}
@@ -0,0 +1,38 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+
+	"github.com/WUMUXIAN/aws-slack-bot/render"
+	"github.com/WUMUXIAN/aws-slack-bot/report"
+)
+
+// File writes reports to a file on disk, replacing the previous report.
+type File struct {
+	Label    string
+	Path     string
+	Renderer render.Renderer
+}
+
+// NewFile creates a file notifier writing reports rendered by given renderer to path.
+func NewFile(label, path string, renderer render.Renderer) File {
+	return File{
+		Label:    label,
+		Path:     path,
+		Renderer: renderer,
+	}
+}
+
+// Name implements Notifier.
+func (o File) Name() string { return o.Label }
+
+// Notify writes the report to the file.
+func (o File) Notify(ctx context.Context, r report.Report) error {
+	content, err := o.Renderer.Render(r)
+	if err != nil {
+		return fmt.Errorf("failed to render report: %s", err.Error())
+	}
+	return ioutil.WriteFile(o.Path, content, 0644)
+}
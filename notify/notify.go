@@ -0,0 +1,47 @@
+// Package notify delivers reports to the destinations they are meant for.
+package notify
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/WUMUXIAN/aws-slack-bot/report"
+)
+
+// Notifier delivers reports to a destination.
+type Notifier interface {
+	// Name identifies the destination in logs and results.
+	Name() string
+	// Notify renders the report in the destination's format and delivers it.
+	Notify(ctx context.Context, r report.Report) error
+}
+
+// Result records the outcome of delivering a report to a destination.
+type Result struct {
+	Destination string
+	Err         error
+	Duration    time.Duration
+}
+
+// Fanout delivers the report to all notifiers concurrently.
+// The results are in the same order as the notifiers.
+func Fanout(ctx context.Context, notifiers []Notifier, r report.Report) []Result {
+	results := make([]Result, len(notifiers))
+	var wg sync.WaitGroup
+	for i, notifier := range notifiers {
+		wg.Add(1)
+		go func(i int, notifier Notifier) {
+			defer wg.Done()
+			start := time.Now()
+			err := notifier.Notify(ctx, r)
+			results[i] = Result{
+				Destination: notifier.Name(),
+				Err:         err,
+				Duration:    time.Since(start),
+			}
+		}(i, notifier)
+	}
+	wg.Wait()
+	return results
+}
@@ -0,0 +1,185 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/WUMUXIAN/aws-slack-bot/render"
+	"github.com/WUMUXIAN/aws-slack-bot/report"
+)
+
+// The default delivery policy of webhooks.
+const (
+	defaultTimeout     = 30 * time.Second
+	defaultMaxAttempts = 5
+	defaultBackoff     = time.Second
+	defaultMaxBackoff  = time.Minute
+)
+
+// StatusError is returned when a webhook responds with a non 2xx status.
+type StatusError struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("webhook responded %d %s: %s", e.StatusCode, http.StatusText(e.StatusCode), e.Body)
+}
+
+// Temporary tells whether the request may succeed if retried, i.e. it was throttled or the server failed.
+func (e *StatusError) Temporary() bool {
+	return e.StatusCode == http.StatusTooManyRequests || e.StatusCode >= 500
+}
+
+// Webhook posts reports to an incoming webhook, such as the ones of Slack, Microsoft Teams and Discord.
+// Throttled and failed posts are retried with exponential backoff, honouring the Retry-After header
+// unless it asks to wait longer than the maximum backoff.
+type Webhook struct {
+	Label       string
+	URL         string
+	Renderer    render.Renderer
+	Client      *http.Client
+	MaxAttempts int
+	Backoff     time.Duration
+	MaxBackoff  time.Duration
+	// Log receives the retries, os.Stdout if nil.
+	Log io.Writer
+}
+
+// NewWebhook creates a webhook notifier posting reports rendered by given renderer.
+func NewWebhook(label, url string, renderer render.Renderer) Webhook {
+	return Webhook{
+		Label:       label,
+		URL:         url,
+		Renderer:    renderer,
+		Client:      &http.Client{Timeout: defaultTimeout},
+		MaxAttempts: defaultMaxAttempts,
+		Backoff:     defaultBackoff,
+		MaxBackoff:  defaultMaxBackoff,
+	}
+}
+
+// Name implements Notifier.
+func (o Webhook) Name() string { return o.Label }
+
+// Notify posts the report, split in as many messages as the format requires, one after another to keep them in order.
+func (o Webhook) Notify(ctx context.Context, r report.Report) error {
+	messages, err := render.Messages(o.Renderer, r)
+	if err != nil {
+		return fmt.Errorf("failed to render report: %s", err.Error())
+	}
+	for i, message := range messages {
+		if err := o.postWithRetry(ctx, message); err != nil {
+			return fmt.Errorf("failed to post message %d of %d: %s", i+1, len(messages), err.Error())
+		}
+	}
+	return nil
+}
+
+// postWithRetry posts a message until it succeeds, fails permanently or runs out of attempts.
+func (o Webhook) postWithRetry(ctx context.Context, message []byte) error {
+	backoff := o.Backoff
+	for attempt := 1; ; attempt++ {
+		retryAfter, err := o.post(ctx, message)
+		if err == nil {
+			return nil
+		}
+		if statusErr, ok := err.(*StatusError); ok && !statusErr.Temporary() {
+			return err
+		}
+		if attempt >= o.MaxAttempts {
+			return fmt.Errorf("giving up after %d attempts: %s", attempt, err.Error())
+		}
+
+		// The webhook is waited for as long as it asks, unless that is past the maximum backoff or the deadline.
+		wait := backoff
+		if o.MaxBackoff > 0 && wait > o.MaxBackoff {
+			wait = o.MaxBackoff
+		}
+		if retryAfter > 0 {
+			wait = retryAfter
+			if o.MaxBackoff > 0 && wait > o.MaxBackoff {
+				return fmt.Errorf("asked to retry in %s, past the maximum backoff of %s, last error: %s", wait, o.MaxBackoff, err.Error())
+			}
+			if deadline, ok := ctx.Deadline(); ok && time.Now().Add(wait).After(deadline) {
+				return fmt.Errorf("asked to retry in %s, past the deadline, last error: %s", wait, err.Error())
+			}
+		}
+		o.logf("Posting to %s failed (%s), retrying in %s\n", o.Label, err.Error(), wait)
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("%s, last error: %s", ctx.Err().Error(), err.Error())
+		case <-time.After(wait):
+		}
+		backoff *= 2
+	}
+}
+
+// logf prints a log line to the webhook's log.
+func (o Webhook) logf(msg string, args ...interface{}) {
+	log := o.Log
+	if log == nil {
+		log = os.Stdout
+	}
+	fmt.Fprintf(log, msg, args...)
+}
+
+// post posts a message once and returns how long the webhook asked to wait before retrying, if it did.
+func (o Webhook) post(ctx context.Context, message []byte) (time.Duration, error) {
+	req, err := http.NewRequest("POST", o.URL, bytes.NewReader(message))
+	if err != nil {
+		return 0, err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Add("content-type", o.Renderer.ContentType())
+	req.Header.Add("cache-control", "no-cache")
+	client := o.Client
+	if client == nil {
+		client = &http.Client{Timeout: defaultTimeout}
+	}
+	res, err := client.Do(req)
+	if err != nil {
+		// Drop the URL from the error, webhook URLs are secrets.
+		if urlErr, ok := err.(*url.Error); ok {
+			return 0, urlErr.Err
+		}
+		return 0, err
+	}
+	defer res.Body.Close()
+	body, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return 0, err
+	}
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return parseRetryAfter(res.Header.Get("Retry-After")), &StatusError{
+			StatusCode: res.StatusCode,
+			Body:       strings.TrimSpace(string(body)),
+		}
+	}
+	return 0, nil
+}
+
+// parseRetryAfter parses the value of a Retry-After header, which is either a number of seconds or a date.
+func parseRetryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		if wait := time.Until(date); wait > 0 {
+			return wait
+		}
+	}
+	return 0
+}
@@ -0,0 +1,164 @@
+package notify
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/WUMUXIAN/aws-slack-bot/render"
+)
+
+// response is a response of the test webhook.
+type response struct {
+	status     int
+	retryAfter string
+}
+
+// webhookServer serves the responses in turn, the last one once they run out, and counts the posts.
+type webhookServer struct {
+	*httptest.Server
+	mu        sync.Mutex
+	responses []response
+	posts     []time.Time
+}
+
+func newWebhookServer(responses ...response) *webhookServer {
+	s := &webhookServer{responses: responses}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		next := s.responses[len(s.responses)-1]
+		if len(s.posts) < len(s.responses) {
+			next = s.responses[len(s.posts)]
+		}
+		s.posts = append(s.posts, time.Now())
+		if next.retryAfter != "" {
+			w.Header().Set("Retry-After", next.retryAfter)
+		}
+		w.WriteHeader(next.status)
+	}))
+	return s
+}
+
+func (s *webhookServer) count() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.posts)
+}
+
+// testWebhook returns a webhook to the URL backing off for a millisecond.
+func testWebhook(url string) Webhook {
+	webhook := NewWebhook("test", url, render.JSON{})
+	webhook.Backoff = time.Millisecond
+	webhook.MaxBackoff = 10 * time.Millisecond
+	webhook.Log = ioutil.Discard
+	return webhook
+}
+
+func TestPostWithRetry(t *testing.T) {
+	tests := []struct {
+		name      string
+		responses []response
+		posts     int
+		err       string
+	}{
+		{"success", []response{{status: 200}}, 1, ""},
+		{"server error", []response{{status: 500}, {status: 502}, {status: 204}}, 3, ""},
+		{"throttled", []response{{status: 429}, {status: 200}}, 2, ""},
+		{"bad request", []response{{status: 400}, {status: 200}}, 1, "400"},
+		{"not found", []response{{status: 404}}, 1, "404"},
+		{"attempt limit", []response{{status: 503}}, defaultMaxAttempts, "giving up after 5 attempts"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			server := newWebhookServer(test.responses...)
+			defer server.Close()
+
+			err := testWebhook(server.URL).postWithRetry(context.Background(), []byte("{}"))
+			if server.count() != test.posts {
+				t.Errorf("got %d posts, want %d", server.count(), test.posts)
+			}
+			if test.err == "" && err != nil {
+				t.Errorf("got error %q, want none", err.Error())
+			}
+			if test.err != "" && (err == nil || !strings.Contains(err.Error(), test.err)) {
+				t.Errorf("got error %v, want %q", err, test.err)
+			}
+		})
+	}
+}
+
+func TestPostWithRetryHonoursRetryAfter(t *testing.T) {
+	server := newWebhookServer(response{status: 429, retryAfter: "1"}, response{status: 200})
+	defer server.Close()
+
+	// The Retry-After is honoured over the backoff, up to the maximum backoff.
+	webhook := testWebhook(server.URL)
+	webhook.MaxBackoff = 2 * time.Second
+	if err := webhook.postWithRetry(context.Background(), []byte("{}")); err != nil {
+		t.Fatal(err)
+	}
+	if server.count() != 2 {
+		t.Fatalf("got %d posts, want 2", server.count())
+	}
+	if waited := server.posts[1].Sub(server.posts[0]); waited < time.Second {
+		t.Errorf("retried after %s, want at least 1s", waited)
+	}
+}
+
+func TestPostWithRetryRetryAfterPastMaxBackoff(t *testing.T) {
+	server := newWebhookServer(response{status: 429, retryAfter: "120"}, response{status: 200})
+	defer server.Close()
+
+	start := time.Now()
+	err := testWebhook(server.URL).postWithRetry(context.Background(), []byte("{}"))
+	if err == nil || !strings.Contains(err.Error(), "past the maximum backoff") {
+		t.Errorf("got error %v, want past the maximum backoff", err)
+	}
+	if time.Since(start) > time.Second {
+		t.Errorf("gave up after %s, want right away", time.Since(start))
+	}
+	if server.count() != 1 {
+		t.Errorf("got %d posts, want 1", server.count())
+	}
+}
+
+func TestPostWithRetryRetryAfterPastDeadline(t *testing.T) {
+	server := newWebhookServer(response{status: 429, retryAfter: "120"}, response{status: 200})
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	start := time.Now()
+	webhook := testWebhook(server.URL)
+	webhook.MaxBackoff = 5 * time.Minute
+	err := webhook.postWithRetry(ctx, []byte("{}"))
+	if err == nil || !strings.Contains(err.Error(), "past the deadline") {
+		t.Errorf("got error %v, want past the deadline", err)
+	}
+	if time.Since(start) > time.Second {
+		t.Errorf("gave up after %s, want right away", time.Since(start))
+	}
+	if server.count() != 1 {
+		t.Errorf("got %d posts, want 1", server.count())
+	}
+}
+
+func TestPostWithRetryNetworkError(t *testing.T) {
+	server := newWebhookServer(response{status: 200})
+	url := server.URL
+	server.Close()
+
+	err := testWebhook(url).postWithRetry(context.Background(), []byte("{}"))
+	if err == nil || !strings.Contains(err.Error(), "giving up after 5 attempts") {
+		t.Fatalf("got error %v, want to give up after 5 attempts", err)
+	}
+	if strings.Contains(err.Error(), url) {
+		t.Errorf("the error %q reveals the webhook URL", err.Error())
+	}
+}
@@ -0,0 +1,161 @@
+package render
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/WUMUXIAN/aws-slack-bot/format"
+	"github.com/WUMUXIAN/aws-slack-bot/report"
+)
+
+// The limits discord puts on a webhook message.
+const (
+	maxDiscordEmbeds      = 10
+	maxDiscordEmbedFields = 25
+	maxDiscordCharacters  = 6000
+	maxDiscordDescription = 4096
+)
+
+// DiscordField defines a field of a discord embed
+type DiscordField struct {
+	Name   string `json:"name"`
+	Value  string `json:"value"`
+	Inline bool   `json:"inline"`
+}
+
+// DiscordEmbed defines a discord embed
+type DiscordEmbed struct {
+	Title       string         `json:"title"`
+	Description string         `json:"description,omitempty"`
+	Color       int            `json:"color,omitempty"`
+	Fields      []DiscordField `json:"fields,omitempty"`
+}
+
+// DiscordMessage defines a discord webhook message
+type DiscordMessage struct {
+	Content string         `json:"content,omitempty"`
+	Embeds  []DiscordEmbed `json:"embeds"`
+}
+
+// ContentType implements Renderer.
+func (DiscordMessage) ContentType() string { return "application/json" }
+
+// Render renders the report as a discord message with one embed per report section and region.
+func (DiscordMessage) Render(r report.Report) ([]byte, error) {
+	message := newDiscordMessage(r)
+	message.Embeds = append(message.Embeds, discordAlertEmbeds(r)...)
+	message.Embeds = append(message.Embeds, discordAccountEmbeds(r)...)
+	for _, g := range groups(r, maxDiscordEmbedFields) {
+		message.Embeds = append(message.Embeds, discordEmbed(g))
+	}
+	message.Embeds = append(message.Embeds, discordProblemEmbeds(r)...)
+	return json.Marshal(message)
+}
+
+// RenderMessages implements Splitter, embeds that don't fit in a message are continued in further messages.
+func (DiscordMessage) RenderMessages(r report.Report) ([][]byte, error) {
+	messages := make([][]byte, 0)
+	message := newDiscordMessage(r)
+	characters := len(message.Content)
+	embeds := append(discordAlertEmbeds(r), discordAccountEmbeds(r)...)
+	for _, g := range groups(r, maxDiscordEmbedFields) {
+		embeds = append(embeds, discordEmbed(g))
+	}
+	embeds = append(embeds, discordProblemEmbeds(r)...)
+	for _, embed := range embeds {
+		if len(message.Embeds) > 0 && (len(message.Embeds) >= maxDiscordEmbeds || characters+embed.characters() > maxDiscordCharacters) {
+			payload, err := json.Marshal(message)
+			if err != nil {
+				return nil, err
+			}
+			messages = append(messages, payload)
+			message = DiscordMessage{Embeds: make([]DiscordEmbed, 0)}
+			characters = 0
+		}
+		message.Embeds = append(message.Embeds, embed)
+		characters += embed.characters()
+	}
+	payload, err := json.Marshal(message)
+	if err != nil {
+		return nil, err
+	}
+	return append(messages, payload), nil
+}
+
+// characters counts the characters of the embed that count towards the discord message limit.
+func (e DiscordEmbed) characters() int {
+	count := len([]rune(e.Title)) + len([]rune(e.Description))
+	for _, field := range e.Fields {
+		count += len([]rune(field.Name)) + len([]rune(field.Value))
+	}
+	return count
+}
+
+func newDiscordMessage(r report.Report) DiscordMessage {
+	return DiscordMessage{
+		Content: fmt.Sprintf("**%s** from %s to %s", r.Heading(),
+			r.Period.Start.Format("2006-01-02"), r.Period.End.Format("2006-01-02")),
+		Embeds: make([]DiscordEmbed, 0),
+	}
+}
+
+// discordAlertEmbeds renders the alerts of the report as embeds listing them, coloured by the most severe.
+func discordAlertEmbeds(r report.Report) []DiscordEmbed {
+	embeds := make([]DiscordEmbed, 0)
+	for i, text := range alertTexts(r, "• ", maxDiscordDescription, nil) {
+		title := alertsTitle
+		if i > 0 {
+			title = continuedTitle(title)
+		}
+		embeds = append(embeds, DiscordEmbed{Title: title, Description: text, Color: discordColor(alertColor(r))})
+	}
+	return embeds
+}
+
+// discordAccountEmbeds renders the account events of the report as embeds listing them.
+func discordAccountEmbeds(r report.Report) []DiscordEmbed {
+	embeds := make([]DiscordEmbed, 0)
+	for i, text := range accountTexts(r, "• ", maxDiscordDescription, nil) {
+		title := accountsTitle
+		if i > 0 {
+			title = continuedTitle(title)
+		}
+		embeds = append(embeds, DiscordEmbed{Title: title, Description: text, Color: discordColor(colorDefault)})
+	}
+	return embeds
+}
+
+// discordProblemEmbeds renders the collection problems of the report as embeds listing them.
+func discordProblemEmbeds(r report.Report) []DiscordEmbed {
+	embeds := make([]DiscordEmbed, 0)
+	for i, text := range problemTexts(r, "• ", maxDiscordDescription, nil) {
+		title := problemsTitle
+		if i > 0 {
+			title = continuedTitle(title)
+		}
+		embeds = append(embeds, DiscordEmbed{Title: title, Description: text, Color: discordColor(colorWarning)})
+	}
+	return embeds
+}
+
+// discordColor turns an RGB hex colour into the integer Discord expects.
+func discordColor(hex string) int {
+	color, _ := strconv.ParseInt(hex, 16, 32)
+	return int(color)
+}
+
+func discordEmbed(g group) DiscordEmbed {
+	embed := DiscordEmbed{
+		Title:       g.title,
+		Description: g.subtitle(),
+		Color:       discordColor(severityColor(g.metrics)),
+	}
+	if len(g.metrics) == 0 {
+		embed.Description = g.empty + "."
+	}
+	for _, metric := range g.metrics {
+		embed.Fields = append(embed.Fields, DiscordField{Name: metric.Label(), Value: format.Value(metric), Inline: true})
+	}
+	return embed
+}
@@ -0,0 +1,54 @@
+package render
+
+import (
+	"github.com/WUMUXIAN/aws-slack-bot/report"
+)
+
+// group is a run of metrics of one section and region, the unit card based formats lay out.
+type group struct {
+	title   string
+	region  string
+	metrics []report.Metric
+	// empty tells why the group has no metrics, if it has none.
+	empty string
+}
+
+// subtitle is the human readable region of the group, empty for global sections.
+func (g group) subtitle() string {
+	if g.region == "" {
+		return ""
+	}
+	return regionDescription(g.region) + " (" + g.region + ")"
+}
+
+// groups breaks the sections of a report into groups of at most max metrics, 0 meaning no limit.
+// Groups continuing a region have their section title marked as continued.
+func groups(r report.Report, max int) []group {
+	groups := make([]group, 0)
+	for _, section := range r.Sections {
+		if len(section.Metrics) == 0 {
+			groups = append(groups, group{title: sectionTitle(section), empty: emptyText(section)})
+			continue
+		}
+		for _, regionMetrics := range section.ByRegion() {
+			region := ""
+			if !section.Global {
+				region = regionMetrics.Region
+			}
+			metrics := regionMetrics.Metrics
+			for start := 0; start < len(metrics); {
+				end := len(metrics)
+				if max > 0 && end-start > max {
+					end = start + max
+				}
+				title := sectionTitle(section)
+				if start > 0 {
+					title = continuedTitle(title)
+				}
+				groups = append(groups, group{title: title, region: region, metrics: metrics[start:end]})
+				start = end
+			}
+		}
+	}
+	return groups
+}
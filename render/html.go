@@ -0,0 +1,68 @@
+package render
+
+import (
+	"bytes"
+	"html/template"
+
+	"github.com/WUMUXIAN/aws-slack-bot/format"
+	"github.com/WUMUXIAN/aws-slack-bot/report"
+)
+
+var htmlTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"region":  regionDescription,
+	"value":   format.Value,
+	"problem": format.Problem,
+	"alert":   format.Alert,
+	"account": format.AccountEvent,
+	"title":   sectionTitle,
+	"empty":   emptyText,
+	"date": func(p report.Period) string {
+		return p.Start.Format("2006-01-02") + " to " + p.End.Format("2006-01-02")
+	},
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Heading}}</title>
+</head>
+<body>
+<h1>{{.Heading}}</h1>
+<p><em>{{date .Period}}</em></p>
+{{if .Alerts}}<h2>Alerts</h2>
+<ul>
+{{range .Alerts}}<li>{{alert .}}</li>
+{{end}}</ul>
+{{end}}{{if .AccountEvents}}<h2>Account changes</h2>
+<ul>
+{{range .AccountEvents}}<li>{{account .}}</li>
+{{end}}</ul>
+{{end}}{{range .Sections}}{{$section := .}}
+<h2>{{title .}}</h2>
+{{if not .Metrics}}<p>{{empty .}}.</p>{{end}}
+{{range .ByRegion}}{{if not $section.Global}}<h3>{{region .Region}} ({{.Region}})</h3>{{end}}
+<table>
+{{range .Metrics}}<tr><td>{{.Label}}</td><td align="right">{{value .}}</td></tr>
+{{end}}</table>
+{{end}}{{end}}
+{{if .Problems}}<h2>Collection problems</h2>
+<ul>
+{{range .Problems}}<li>{{problem .}}</li>
+{{end}}</ul>
+{{end}}</body>
+</html>
+`))
+
+// HTML renders reports as a standalone HTML document, e.g. for emails.
+type HTML struct{}
+
+// ContentType implements Renderer.
+func (HTML) ContentType() string { return "text/html; charset=utf-8" }
+
+// Render renders the report with one heading per section and one table per region.
+func (HTML) Render(r report.Report) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := htmlTemplate.Execute(&buf, r); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
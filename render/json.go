@@ -0,0 +1,18 @@
+package render
+
+import (
+	"encoding/json"
+
+	"github.com/WUMUXIAN/aws-slack-bot/report"
+)
+
+// JSON renders reports as the JSON encoding of the report model, for machines to consume.
+type JSON struct{}
+
+// ContentType implements Renderer.
+func (JSON) ContentType() string { return "application/json" }
+
+// Render renders the report as indented JSON.
+func (JSON) Render(r report.Report) ([]byte, error) {
+	return json.MarshalIndent(r, "", "  ")
+}
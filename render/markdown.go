@@ -0,0 +1,63 @@
+package render
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/WUMUXIAN/aws-slack-bot/format"
+	"github.com/WUMUXIAN/aws-slack-bot/report"
+)
+
+// Markdown renders reports as a markdown document.
+type Markdown struct{}
+
+// ContentType implements Renderer.
+func (Markdown) ContentType() string { return "text/markdown; charset=utf-8" }
+
+// Render renders the report with one heading per section and one table per region.
+func (Markdown) Render(r report.Report) ([]byte, error) {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "# %s\n\n", r.Heading())
+	fmt.Fprintf(&buf, "_%s to %s_\n", r.Period.Start.Format("2006-01-02"), r.Period.End.Format("2006-01-02"))
+	if len(r.Alerts) > 0 {
+		fmt.Fprintf(&buf, "\n## %s\n\n", alertsTitle)
+		for _, alert := range r.Alerts {
+			fmt.Fprintf(&buf, "- %s\n", format.Alert(alert))
+		}
+	}
+	if len(r.AccountEvents) > 0 {
+		fmt.Fprintf(&buf, "\n## %s\n\n", accountsTitle)
+		for _, event := range r.AccountEvents {
+			fmt.Fprintf(&buf, "- %s\n", format.AccountEvent(event))
+		}
+	}
+	for _, section := range r.Sections {
+		fmt.Fprintf(&buf, "\n## %s\n", sectionTitle(section))
+		if len(section.Metrics) == 0 {
+			fmt.Fprintf(&buf, "\n%s.\n", emptyText(section))
+			continue
+		}
+		for _, regionMetrics := range section.ByRegion() {
+			if !section.Global {
+				fmt.Fprintf(&buf, "\n### %s (%s)\n", regionDescription(regionMetrics.Region), regionMetrics.Region)
+			}
+			fmt.Fprintf(&buf, "\n| Metric | Value |\n|:--|--:|\n")
+			for _, metric := range regionMetrics.Metrics {
+				fmt.Fprintf(&buf, "| %s | %s |\n", cell(metric.Label()), cell(format.Value(metric)))
+			}
+		}
+	}
+	if len(r.Problems) > 0 {
+		fmt.Fprintf(&buf, "\n## %s\n\n", problemsTitle)
+		for _, problem := range r.Problems {
+			fmt.Fprintf(&buf, "- %s\n", format.Problem(problem))
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+// cell escapes the pipes of a table cell, e.g. in a tag value, which would end the cell.
+func cell(text string) string {
+	return strings.Replace(text, "|", "\\|", -1)
+}
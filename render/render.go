@@ -0,0 +1,179 @@
+// Package render turns a report into the payload sent to a destination.
+package render
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/WUMUXIAN/aws-slack-bot/format"
+	"github.com/WUMUXIAN/aws-slack-bot/report"
+	"github.com/aws/aws-sdk-go/aws/endpoints"
+)
+
+// Renderer renders a report into a payload.
+type Renderer interface {
+	// ContentType is the MIME type of the rendered payload.
+	ContentType() string
+	// Render renders the given report.
+	Render(r report.Report) ([]byte, error)
+}
+
+var renderers = map[string]Renderer{
+	"attachments": SlackAttachments{},
+	"blocks":      SlackBlocks{},
+	"teams":       TeamsCard{},
+	"discord":     DiscordMessage{},
+	"markdown":    Markdown{},
+	"json":        JSON{},
+	"html":        HTML{},
+}
+
+// New returns the renderer of given format name.
+func New(name string) (Renderer, error) {
+	renderer, ok := renderers[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown format %q, available formats are: %s", name, strings.Join(Names(), ","))
+	}
+	return renderer, nil
+}
+
+// Names returns the names of all formats in alphabetical order.
+func Names() []string {
+	names := make([]string, 0, len(renderers))
+	for name := range renderers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// sectionTitle is the title of a section, preceded by its account if any and marked if the section failed or is partial.
+func sectionTitle(section report.Section) string {
+	title := section.Title
+	if section.Account != "" {
+		title = section.Account + ": " + title
+	}
+	if section.Failed {
+		return title + " (failed)"
+	}
+	if section.Partial {
+		return title + " (partial)"
+	}
+	return title
+}
+
+// emptyText tells why a section has no metrics, without a full stop.
+func emptyText(section report.Section) string {
+	if section.Failed {
+		return "Could not collect, see the collection problems"
+	}
+	return "Nothing to report"
+}
+
+// problemsTitle is the heading of the section listing collection problems.
+const problemsTitle = "Collection problems"
+
+// alertsTitle is the heading of the section listing the alerts.
+const alertsTitle = "Alerts"
+
+// accountsTitle is the heading of the section listing the account events.
+const accountsTitle = "Account changes"
+
+// problemTexts joins the descriptions of the report's problems, one per line prefixed by bullet,
+// escaped by escape if set, into texts of at most max characters each.
+func problemTexts(r report.Report, bullet string, max int, escape func(string) string) []string {
+	lines := make([]string, 0, len(r.Problems))
+	for _, problem := range r.Problems {
+		lines = append(lines, format.Problem(problem))
+	}
+	return joinLines(lines, bullet, max, escape)
+}
+
+// alertTexts joins the descriptions of the report's alerts, one per line prefixed by bullet,
+// escaped by escape if set, into texts of at most max characters each.
+func alertTexts(r report.Report, bullet string, max int, escape func(string) string) []string {
+	lines := make([]string, 0, len(r.Alerts))
+	for _, alert := range r.Alerts {
+		lines = append(lines, format.Alert(alert))
+	}
+	return joinLines(lines, bullet, max, escape)
+}
+
+// accountTexts joins the descriptions of the report's account events, one per line prefixed by bullet,
+// escaped by escape if set, into texts of at most max characters each.
+func accountTexts(r report.Report, bullet string, max int, escape func(string) string) []string {
+	lines := make([]string, 0, len(r.AccountEvents))
+	for _, event := range r.AccountEvents {
+		lines = append(lines, format.AccountEvent(event))
+	}
+	return joinLines(lines, bullet, max, escape)
+}
+
+// alertColor returns the colour of the most severe alert of the report.
+func alertColor(r report.Report) string {
+	for _, alert := range r.Alerts {
+		if alert.Severity == report.Critical {
+			return colorCritical
+		}
+	}
+	return colorWarning
+}
+
+// joinLines joins lines, each prefixed by bullet and escaped by escape if set, into texts of at most max characters each.
+// The lines are escaped before they are measured, as escaping lengthens them.
+func joinLines(lines []string, bullet string, max int, escape func(string) string) []string {
+	texts := make([]string, 0)
+	current := ""
+	for _, line := range lines {
+		if escape != nil {
+			line = escape(line)
+		}
+		line = bullet + line
+		if current != "" && len(current)+1+len(line) > max {
+			texts = append(texts, current)
+			current = ""
+		}
+		if current != "" {
+			current += "\n"
+		}
+		current += line
+	}
+	if current != "" {
+		texts = append(texts, current)
+	}
+	return texts
+}
+
+// The colours of metrics by severity, as RGB hex.
+const (
+	colorDefault  = "D00000"
+	colorCompared = "2EB886"
+	colorWarning  = "DAA038"
+	colorCritical = "A30200"
+)
+
+// severityColor returns the colour of the most severe of the metrics,
+// green if they were compared with an earlier report without exceeding the thresholds.
+func severityColor(metrics []report.Metric) string {
+	switch (report.Section{Metrics: metrics}).Severity() {
+	case report.Critical:
+		return colorCritical
+	case report.Warning:
+		return colorWarning
+	}
+	for _, metric := range metrics {
+		if len(metric.Changes) > 0 {
+			return colorCompared
+		}
+	}
+	return colorDefault
+}
+
+// regionDescription returns the human readable name of a region, e.g. "US East (N. Virginia)".
+func regionDescription(region string) string {
+	if partitionRegion, ok := endpoints.AwsPartition().Regions()[region]; ok {
+		return partitionRegion.Description()
+	}
+	return region
+}
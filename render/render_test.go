@@ -0,0 +1,37 @@
+package render
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/WUMUXIAN/aws-slack-bot/report"
+)
+
+func TestFailedSections(t *testing.T) {
+	r := report.Report{Sections: []report.Section{
+		{Service: "s3", Title: "S3 Usage", Failed: true},
+		{Service: "ec2", Title: "EC2 Usage"},
+		{Service: "rds", Title: "RDS Usage", Global: true, Failed: true, Partial: true, Metrics: []report.Metric{{Service: "rds", Name: "Instances", Value: 2, Unit: report.Count}}},
+	}}
+	for _, name := range []string{"markdown", "html", "attachments", "blocks", "teams", "discord"} {
+		t.Run(name, func(t *testing.T) {
+			renderer, err := New(name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			payload, err := renderer.Render(r)
+			if err != nil {
+				t.Fatal(err)
+			}
+			text := string(payload)
+			for _, want := range []string{"S3 Usage (failed)", "Could not collect", "EC2 Usage", "Nothing to report", "RDS Usage (failed)"} {
+				if !strings.Contains(text, want) {
+					t.Errorf("%q is not in %s", want, text)
+				}
+			}
+			if strings.Contains(text, "EC2 Usage (failed)") {
+				t.Errorf("the section without problems is marked as failed in %s", text)
+			}
+		})
+	}
+}
@@ -0,0 +1,209 @@
+package render
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/WUMUXIAN/aws-slack-bot/format"
+	"github.com/WUMUXIAN/aws-slack-bot/report"
+)
+
+// SlackAttachmentField defines a slack attachment field
+type SlackAttachmentField struct {
+	Title string `json:"title"`
+	Value string `json:"value"`
+	Short bool   `json:"short"`
+}
+
+// SlackAttachment defines a slack attachment
+type SlackAttachment struct {
+	Fallback   string                 `json:"fallback,omitempty"`
+	Color      string                 `json:"color,omitempty"`
+	PreText    string                 `json:"pretext,omitempty"`
+	AuthorName string                 `json:"author_name,omitempty"`
+	AuthorLink string                 `json:"author_link,omitempty"`
+	AuthorIcon string                 `json:"author_icon,omitempty"`
+	Title      string                 `json:"title,omitempty"`
+	TitleLink  string                 `json:"title_link,omitempty"`
+	Text       string                 `json:"text,omitempty"`
+	Fields     []SlackAttachmentField `json:"fields,omitempty"`
+	ImageURL   string                 `json:"image_url,omitempty"`
+	ThumbURL   string                 `json:"thumb_url,omitempty"`
+	Footer     string                 `json:"footer,omitempty"`
+	FooterIcon string                 `json:"footer_icon,omitempty"`
+	TS         int64                  `json:"ts,omitempty"`
+}
+
+// SlackAttachments defines slack attachments
+type SlackAttachments struct {
+	Attacments []SlackAttachment `json:"attachments"`
+}
+
+// ContentType implements Renderer.
+func (SlackAttachments) ContentType() string { return "application/json" }
+
+// Render renders the report as a legacy slack message with one attachment per section.
+func (SlackAttachments) Render(r report.Report) ([]byte, error) {
+	slackAttachments := append(alertAttachments(r), accountAttachments(r)...)
+	for _, section := range r.Sections {
+		slackAttachments = append(slackAttachments, sectionAttachments(section, 0)...)
+	}
+	slackAttachments = append(slackAttachments, problemAttachments(r)...)
+	return json.Marshal(SlackAttachments{Attacments: slackAttachments})
+}
+
+// RenderMessages implements Splitter, sections with too many fields are continued in further attachments
+// and attachments that don't fit in a message are continued in further messages.
+func (SlackAttachments) RenderMessages(r report.Report) ([][]byte, error) {
+	messages := make([][]byte, 0)
+	current := make([]SlackAttachment, 0)
+	currentSize := 0
+	flush := func() error {
+		payload, err := json.Marshal(SlackAttachments{Attacments: current})
+		if err != nil {
+			return err
+		}
+		messages = append(messages, payload)
+		current = make([]SlackAttachment, 0)
+		currentSize = 0
+		return nil
+	}
+
+	attachments := append(alertAttachments(r), accountAttachments(r)...)
+	for _, section := range r.Sections {
+		attachments = append(attachments, sectionAttachments(section, maxAttachmentFields)...)
+	}
+	attachments = append(attachments, problemAttachments(r)...)
+	for _, attachment := range attachments {
+		attachmentBytes, err := json.Marshal(attachment)
+		if err != nil {
+			return nil, err
+		}
+		size := len(attachmentBytes) + 1
+		if len(current) > 0 && (len(current) >= maxMessageAttachments || currentSize+size > maxMessageBytes) {
+			if err := flush(); err != nil {
+				return nil, err
+			}
+		}
+		current = append(current, attachment)
+		currentSize += size
+	}
+	if err := flush(); err != nil {
+		return nil, err
+	}
+	return messages, nil
+}
+
+// sectionAttachments renders a section as attachments of at most maxFields fields, 0 meaning no limit.
+// A region split across attachments has its heading repeated in the continued attachment.
+func sectionAttachments(section report.Section, maxFields int) []SlackAttachment {
+	color := "#" + severityColor(section.Metrics)
+	newAttachment := func(title string) SlackAttachment {
+		return SlackAttachment{
+			Fallback: title,
+			PreText:  title,
+			Color:    color,
+			Fields:   make([]SlackAttachmentField, 0),
+		}
+	}
+	attachments := []SlackAttachment{newAttachment(sectionTitle(section))}
+	if len(section.Metrics) == 0 {
+		attachments[0].Text = emptyText(section) + "."
+	}
+	full := func(reserved int) bool {
+		return maxFields > 0 && len(attachments[len(attachments)-1].Fields)+reserved >= maxFields
+	}
+
+	for _, regionMetrics := range section.ByRegion() {
+		var regionField []SlackAttachmentField
+		if !section.Global {
+			regionField = []SlackAttachmentField{{
+				Title: "",
+				Value: fmt.Sprintf("_&lt;%s: %s&gt;_", regionDescription(regionMetrics.Region), regionMetrics.Region),
+				Short: false,
+			}}
+			// Don't leave a region heading alone at the end of an attachment.
+			if full(1) {
+				attachments = append(attachments, newAttachment(continuedTitle(sectionTitle(section))))
+			}
+			last := &attachments[len(attachments)-1]
+			last.Fields = append(last.Fields, regionField...)
+		}
+		for _, field := range getSlackAttachmentFields(regionMetrics.Metrics) {
+			if full(0) {
+				attachments = append(attachments, newAttachment(continuedTitle(sectionTitle(section))))
+				last := &attachments[len(attachments)-1]
+				last.Fields = append(last.Fields, regionField...)
+			}
+			last := &attachments[len(attachments)-1]
+			last.Fields = append(last.Fields, field)
+		}
+	}
+	return attachments
+}
+
+// alertAttachments renders the alerts of the report as attachments listing them, coloured by the most severe.
+func alertAttachments(r report.Report) []SlackAttachment {
+	attachments := make([]SlackAttachment, 0)
+	for i, text := range alertTexts(r, "• ", maxSectionText, escapeSlack) {
+		title := alertsTitle
+		if i > 0 {
+			title = continuedTitle(title)
+		}
+		attachments = append(attachments, SlackAttachment{
+			Fallback: title,
+			PreText:  title,
+			Color:    "#" + alertColor(r),
+			Text:     text,
+		})
+	}
+	return attachments
+}
+
+// accountAttachments renders the account events of the report as attachments listing them.
+func accountAttachments(r report.Report) []SlackAttachment {
+	attachments := make([]SlackAttachment, 0)
+	for i, text := range accountTexts(r, "• ", maxSectionText, escapeSlack) {
+		title := accountsTitle
+		if i > 0 {
+			title = continuedTitle(title)
+		}
+		attachments = append(attachments, SlackAttachment{
+			Fallback: title,
+			PreText:  title,
+			Color:    "#" + colorDefault,
+			Text:     text,
+		})
+	}
+	return attachments
+}
+
+// problemAttachments renders the collection problems of the report as attachments listing them.
+func problemAttachments(r report.Report) []SlackAttachment {
+	attachments := make([]SlackAttachment, 0)
+	for i, text := range problemTexts(r, "• ", maxSectionText, escapeSlack) {
+		title := problemsTitle
+		if i > 0 {
+			title = continuedTitle(title)
+		}
+		attachments = append(attachments, SlackAttachment{
+			Fallback: title,
+			PreText:  title,
+			Color:    "warning",
+			Text:     text,
+		})
+	}
+	return attachments
+}
+
+func getSlackAttachmentFields(metrics []report.Metric) []SlackAttachmentField {
+	fields := make([]SlackAttachmentField, 0)
+	for _, metric := range metrics {
+		fields = append(fields, SlackAttachmentField{
+			Title: metric.Label(),
+			Value: format.Value(metric),
+			Short: true,
+		})
+	}
+	return fields
+}
@@ -0,0 +1,248 @@
+package render
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/WUMUXIAN/aws-slack-bot/format"
+	"github.com/WUMUXIAN/aws-slack-bot/report"
+)
+
+// The limits slack puts on a section block.
+const (
+	maxSectionFields = 10
+	maxSectionText   = 3000
+)
+
+// SlackText defines a slack text object
+type SlackText struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+// SlackBlock defines a slack layout block
+type SlackBlock struct {
+	Type     string      `json:"type"`
+	Text     *SlackText  `json:"text,omitempty"`
+	Fields   []SlackText `json:"fields,omitempty"`
+	Elements []SlackText `json:"elements,omitempty"`
+}
+
+// SlackBlocks defines a slack block kit message
+type SlackBlocks struct {
+	Text   string       `json:"text"`
+	Blocks []SlackBlock `json:"blocks"`
+}
+
+// ContentType implements Renderer.
+func (SlackBlocks) ContentType() string { return "application/json" }
+
+// Render renders the report as a slack block kit message, with a header per section and a fields block per region.
+func (SlackBlocks) Render(r report.Report) ([]byte, error) {
+	return json.Marshal(blockMessages(r, false)[0])
+}
+
+// RenderMessages implements Splitter, blocks that don't fit in a message are continued in further messages.
+func (SlackBlocks) RenderMessages(r report.Report) ([][]byte, error) {
+	messages := make([][]byte, 0)
+	for _, message := range blockMessages(r, true) {
+		payload, err := json.Marshal(message)
+		if err != nil {
+			return nil, err
+		}
+		messages = append(messages, payload)
+	}
+	return messages, nil
+}
+
+// blockChunk is a block of a section together with the region it belongs to, empty for global sections.
+type blockChunk struct {
+	region string
+	block  SlackBlock
+}
+
+// blockMessages lays the report out in block kit messages, starting a new message whenever
+// the slack limits would be exceeded if limited is set.
+func blockMessages(r report.Report, limited bool) []SlackBlocks {
+	first := SlackBlocks{Text: r.Heading(), Blocks: []SlackBlock{
+		{Type: "header", Text: plainText(r.Heading())},
+		contextBlock(fmt.Sprintf("AWS usage from %s to %s",
+			r.Period.Start.Format("2006-01-02"), r.Period.End.Format("2006-01-02"))),
+	}}
+	messages := []SlackBlocks{first}
+	size := blocksSize(first.Blocks)
+
+	// The alerts and account events are laid out as more sections at the start of the report, the problems at the end.
+	sections := make([]report.Section, 0, len(r.Sections)+3)
+	chunks := make([][]blockChunk, 0, len(r.Sections)+3)
+	if len(r.Alerts) > 0 {
+		sections = append(sections, report.Section{Title: alertsTitle, Global: true})
+		chunks = append(chunks, alertChunks(r))
+	}
+	if len(r.AccountEvents) > 0 {
+		sections = append(sections, report.Section{Title: accountsTitle, Global: true})
+		chunks = append(chunks, accountChunks(r))
+	}
+	for _, section := range r.Sections {
+		sections = append(sections, section)
+		chunks = append(chunks, sectionChunks(section))
+	}
+	if len(r.Problems) > 0 {
+		sections = append(sections, report.Section{Title: problemsTitle, Global: true})
+		chunks = append(chunks, problemChunks(r))
+	}
+
+	// The section and region whose headings were last written to the current message.
+	headingSection, headingRegion := -1, ""
+	for i, section := range sections {
+		continued := false
+		for _, chunk := range chunks[i] {
+			blocks := chunkBlocks(section, chunk, headingSection == i, headingRegion == chunk.region, continued)
+			current := &messages[len(messages)-1]
+			if limited && len(current.Blocks) > 0 && (len(current.Blocks)+len(blocks) > maxMessageBlocks || size+blocksSize(blocks) > maxMessageBytes) {
+				messages = append(messages, SlackBlocks{Text: continuedTitle(r.Heading()), Blocks: make([]SlackBlock, 0)})
+				current = &messages[len(messages)-1]
+				size = 0
+				blocks = chunkBlocks(section, chunk, false, false, continued)
+			}
+			current.Blocks = append(current.Blocks, blocks...)
+			size += blocksSize(blocks)
+			headingSection, headingRegion = i, chunk.region
+			continued = true
+		}
+	}
+	return messages
+}
+
+// sectionChunks breaks a section into the blocks holding its metrics.
+func sectionChunks(section report.Section) []blockChunk {
+	if len(section.Metrics) == 0 {
+		return []blockChunk{{block: contextBlock("_" + emptyText(section) + "_")}}
+	}
+	chunks := make([]blockChunk, 0)
+	for _, regionMetrics := range section.ByRegion() {
+		region := ""
+		if !section.Global {
+			region = regionMetrics.Region
+		}
+		for _, block := range fieldBlocks(regionMetrics.Metrics) {
+			chunks = append(chunks, blockChunk{region: region, block: block})
+		}
+	}
+	return chunks
+}
+
+// alertChunks lists the alerts of the report in as many blocks as their length requires.
+func alertChunks(r report.Report) []blockChunk {
+	chunks := make([]blockChunk, 0)
+	for _, text := range alertTexts(r, "• ", maxSectionText, escapeSlack) {
+		chunks = append(chunks, blockChunk{block: SlackBlock{
+			Type: "section",
+			Text: &SlackText{Type: "mrkdwn", Text: text},
+		}})
+	}
+	return chunks
+}
+
+// accountChunks lists the account events of the report in as many blocks as their length requires.
+func accountChunks(r report.Report) []blockChunk {
+	chunks := make([]blockChunk, 0)
+	for _, text := range accountTexts(r, "• ", maxSectionText, escapeSlack) {
+		chunks = append(chunks, blockChunk{block: SlackBlock{
+			Type: "section",
+			Text: &SlackText{Type: "mrkdwn", Text: text},
+		}})
+	}
+	return chunks
+}
+
+// problemChunks lists the collection problems of the report in as many blocks as their length requires.
+func problemChunks(r report.Report) []blockChunk {
+	chunks := make([]blockChunk, 0)
+	for _, text := range problemTexts(r, "• ", maxSectionText, escapeSlack) {
+		chunks = append(chunks, blockChunk{block: SlackBlock{
+			Type: "section",
+			Text: &SlackText{Type: "mrkdwn", Text: text},
+		}})
+	}
+	return chunks
+}
+
+// chunkBlocks returns the chunk's block preceded by the section and region headings not yet written.
+func chunkBlocks(section report.Section, chunk blockChunk, sectionWritten, regionWritten, continued bool) []SlackBlock {
+	blocks := make([]SlackBlock, 0, 4)
+	if !sectionWritten {
+		title := sectionTitle(section)
+		if continued {
+			title = continuedTitle(title)
+		}
+		blocks = append(blocks, SlackBlock{Type: "divider"}, SlackBlock{Type: "header", Text: plainText(title)})
+	}
+	if chunk.region != "" && (!sectionWritten || !regionWritten) {
+		blocks = append(blocks, contextBlock(fmt.Sprintf(":round_pushpin: %s (%s)",
+			regionDescription(chunk.region), chunk.region)))
+	}
+	return append(blocks, chunk.block)
+}
+
+// blocksSize estimates the number of bytes the blocks take in a message.
+func blocksSize(blocks []SlackBlock) int {
+	size := 0
+	for _, block := range blocks {
+		blockBytes, _ := json.Marshal(block)
+		size += len(blockBytes) + 1
+	}
+	return size
+}
+
+// severityEmoji marks the metrics that need attention.
+var severityEmoji = map[report.Severity]string{
+	report.Warning:  ":warning: ",
+	report.Critical: ":rotating_light: ",
+}
+
+// fieldBlocks renders metrics as section blocks of fields, as many as the field limit requires.
+func fieldBlocks(metrics []report.Metric) []SlackBlock {
+	blocks := make([]SlackBlock, 0)
+	for start := 0; start < len(metrics); start += maxSectionFields {
+		end := start + maxSectionFields
+		if end > len(metrics) {
+			end = len(metrics)
+		}
+		block := SlackBlock{Type: "section", Fields: make([]SlackText, 0, end-start)}
+		for _, metric := range metrics[start:end] {
+			block.Fields = append(block.Fields, SlackText{
+				Type: "mrkdwn",
+				Text: fmt.Sprintf("%s*%s*\n%s", severityEmoji[metric.Severity], escapeSlack(metric.Label()), escapeSlack(format.Value(metric))),
+			})
+		}
+		blocks = append(blocks, block)
+	}
+	return blocks
+}
+
+// maxHeaderLength is the number of characters slack accepts in a header block.
+const maxHeaderLength = 150
+
+// plainText returns a plain text object fit for a header block.
+func plainText(text string) *SlackText {
+	if runes := []rune(text); len(runes) > maxHeaderLength {
+		text = string(runes[:maxHeaderLength-3]) + "..."
+	}
+	return &SlackText{Type: "plain_text", Text: text}
+}
+
+func contextBlock(text string) SlackBlock {
+	return SlackBlock{
+		Type:     "context",
+		Elements: []SlackText{{Type: "mrkdwn", Text: text}},
+	}
+}
+
+var slackEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+// escapeSlack escapes the control characters of slack mrkdwn text.
+func escapeSlack(text string) string {
+	return slackEscaper.Replace(text)
+}
@@ -0,0 +1,37 @@
+package render
+
+import (
+	"github.com/WUMUXIAN/aws-slack-bot/report"
+)
+
+// The limits slack puts on a single message, messages over them are rejected as a whole.
+const (
+	maxMessageBytes       = 40000
+	maxMessageBlocks      = 50
+	maxMessageAttachments = 100
+	maxAttachmentFields   = 50
+)
+
+// Splitter is implemented by renderers whose payloads are size limited.
+type Splitter interface {
+	// RenderMessages renders the report into as many payloads as the limits require,
+	// repeating the section and region headings in the payload a section continues in.
+	RenderMessages(r report.Report) ([][]byte, error)
+}
+
+// Messages renders the report into the payloads to send, split if the renderer supports it.
+func Messages(renderer Renderer, r report.Report) ([][]byte, error) {
+	if splitter, ok := renderer.(Splitter); ok {
+		return splitter.RenderMessages(r)
+	}
+	payload, err := renderer.Render(r)
+	if err != nil {
+		return nil, err
+	}
+	return [][]byte{payload}, nil
+}
+
+// continuedTitle is the title of a section continued from a previous message or attachment.
+func continuedTitle(title string) string {
+	return title + " (continued)"
+}
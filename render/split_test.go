@@ -0,0 +1,177 @@
+package render
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/WUMUXIAN/aws-slack-bot/report"
+)
+
+// oversizedReport returns a report over every slack limit: more metrics than fit in a message,
+// alerts and problems longer than a section text, in several regions.
+func oversizedReport() report.Report {
+	period := report.MonthOf(time.Date(2026, 10, 17, 0, 0, 0, 0, time.UTC))
+	r := report.Report{Period: period, GeneratedAt: period.Start}
+	buckets := report.Section{Service: "s3", Title: "S3 Usage", Metrics: make([]report.Metric, 0)}
+	for _, region := range []string{"us-east-1", "ap-southeast-1", "eu-west-1"} {
+		for i := 0; i < 400; i++ {
+			buckets.Metrics = append(buckets.Metrics, report.Metric{
+				Service:    "s3",
+				Region:     region,
+				Name:       "Bucket Size",
+				Value:      float64(i) * 1e6,
+				Unit:       report.Bytes,
+				Dimensions: map[string]string{"BucketName": fmt.Sprintf("%s-bucket-%04d", region, i)},
+			})
+		}
+	}
+	r.Sections = append(r.Sections, buckets)
+	for i := 0; i < 100; i++ {
+		metric := report.Metric{Service: "billing", Name: fmt.Sprintf("Charges %03d", i), Value: 100, Unit: report.USD}
+		r.Alerts = append(r.Alerts, report.Alert{Rule: strings.Repeat("Budget ", 10), Severity: report.Warning, Condition: "above", Threshold: 50, Metric: metric})
+		r.Problems = append(r.Problems, report.Problem{Service: "ec2", Region: "us-east-1", API: "ec2:DescribeInstances", Code: "Throttling", Message: strings.Repeat("slow down ", 15)})
+	}
+	return r
+}
+
+// labels lists the labels of the metrics of the report.
+func labels(r report.Report) []string {
+	labels := make([]string, 0)
+	for _, section := range r.Sections {
+		for _, metric := range section.Metrics {
+			labels = append(labels, metric.Label())
+		}
+	}
+	return labels
+}
+
+func TestSlackBlocksSplitWithinLimits(t *testing.T) {
+	r := oversizedReport()
+	messages, err := SlackBlocks{}.RenderMessages(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(messages) < 2 {
+		t.Fatalf("got %d message, want the report split", len(messages))
+	}
+
+	fields := make(map[string]int)
+	for i, payload := range messages {
+		if len(payload) > maxMessageBytes {
+			t.Errorf("message %d: %d bytes, over %d", i, len(payload), maxMessageBytes)
+		}
+		var message SlackBlocks
+		if err := json.Unmarshal(payload, &message); err != nil {
+			t.Fatal(err)
+		}
+		if len(message.Blocks) > maxMessageBlocks {
+			t.Errorf("message %d: %d blocks, over %d", i, len(message.Blocks), maxMessageBlocks)
+		}
+		for _, block := range message.Blocks {
+			if block.Text != nil && len(block.Text.Text) > maxSectionText {
+				t.Errorf("message %d: %d characters in a %s block, over %d", i, len(block.Text.Text), block.Type, maxSectionText)
+			}
+			if len(block.Fields) > maxSectionFields {
+				t.Errorf("message %d: %d fields in a block, over %d", i, len(block.Fields), maxSectionFields)
+			}
+			for _, field := range block.Fields {
+				// The label is the bold part of the field, after the severity.
+				if parts := strings.SplitN(field.Text, "*", 3); len(parts) == 3 {
+					fields[parts[1]]++
+				}
+			}
+		}
+	}
+	for _, label := range labels(r) {
+		if fields[label] != 1 {
+			t.Errorf("metric %s is in %d fields, want 1", label, fields[label])
+		}
+	}
+}
+
+func TestSlackAttachmentsSplitWithinLimits(t *testing.T) {
+	r := oversizedReport()
+	messages, err := SlackAttachments{}.RenderMessages(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(messages) < 2 {
+		t.Fatalf("got %d message, want the report split", len(messages))
+	}
+
+	fields := make(map[string]int)
+	for i, payload := range messages {
+		if len(payload) > maxMessageBytes {
+			t.Errorf("message %d: %d bytes, over %d", i, len(payload), maxMessageBytes)
+		}
+		var message SlackAttachments
+		if err := json.Unmarshal(payload, &message); err != nil {
+			t.Fatal(err)
+		}
+		if len(message.Attacments) > maxMessageAttachments {
+			t.Errorf("message %d: %d attachments, over %d", i, len(message.Attacments), maxMessageAttachments)
+		}
+		for _, attachment := range message.Attacments {
+			if len(attachment.Fields) > maxAttachmentFields {
+				t.Errorf("message %d: %d fields in an attachment, over %d", i, len(attachment.Fields), maxAttachmentFields)
+			}
+			if len(attachment.Text) > maxSectionText {
+				t.Errorf("message %d: %d characters in an attachment, over %d", i, len(attachment.Text), maxSectionText)
+			}
+			for _, field := range attachment.Fields {
+				if field.Title != "" {
+					fields[field.Title]++
+				}
+			}
+		}
+	}
+	for _, label := range labels(r) {
+		if fields[label] != 1 {
+			t.Errorf("metric %s is in %d fields, want 1", label, fields[label])
+		}
+	}
+}
+
+func TestSlackTextsEscapedWithinLimits(t *testing.T) {
+	// Every & is escaped to &amp;, five times its length.
+	r := report.Report{Period: report.MonthOf(time.Date(2026, 10, 17, 0, 0, 0, 0, time.UTC))}
+	for i := 0; i < 100; i++ {
+		r.Problems = append(r.Problems, report.Problem{Service: "s3", API: "s3:ListBuckets", Message: strings.Repeat("&", 100)})
+	}
+	blocks, err := SlackBlocks{}.RenderMessages(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, payload := range blocks {
+		var message SlackBlocks
+		if err := json.Unmarshal(payload, &message); err != nil {
+			t.Fatal(err)
+		}
+		for _, block := range message.Blocks {
+			if block.Text != nil && len(block.Text.Text) > maxSectionText {
+				t.Errorf("message %d: %d characters in a %s block, over %d", i, len(block.Text.Text), block.Type, maxSectionText)
+			}
+		}
+	}
+	attachments, err := SlackAttachments{}.RenderMessages(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, payload := range attachments {
+		var message SlackAttachments
+		if err := json.Unmarshal(payload, &message); err != nil {
+			t.Fatal(err)
+		}
+		for _, attachment := range message.Attacments {
+			if len(attachment.Text) > maxSectionText {
+				t.Errorf("message %d: %d characters in an attachment, over %d", i, len(attachment.Text), maxSectionText)
+			}
+			if strings.Contains(strings.Replace(attachment.Text, "&amp;", "", -1), "&") {
+				t.Errorf("message %d: an & is not escaped", i)
+			}
+		}
+	}
+}
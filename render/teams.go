@@ -0,0 +1,168 @@
+package render
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/WUMUXIAN/aws-slack-bot/format"
+	"github.com/WUMUXIAN/aws-slack-bot/report"
+)
+
+// The limits microsoft teams puts on a connector card.
+const (
+	maxTeamsCardBytes = 25000
+	maxTeamsFacts     = 50
+)
+
+// TeamsFact defines a name and value pair of a teams card section
+type TeamsFact struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// TeamsSection defines a section of a teams card
+type TeamsSection struct {
+	ActivityTitle    string      `json:"activityTitle"`
+	ActivitySubtitle string      `json:"activitySubtitle,omitempty"`
+	Text             string      `json:"text,omitempty"`
+	Facts            []TeamsFact `json:"facts,omitempty"`
+}
+
+// TeamsCard defines a microsoft teams connector card
+type TeamsCard struct {
+	Type       string         `json:"@type"`
+	Context    string         `json:"@context"`
+	Summary    string         `json:"summary"`
+	Title      string         `json:"title"`
+	Text       string         `json:"text,omitempty"`
+	ThemeColor string         `json:"themeColor,omitempty"`
+	Sections   []TeamsSection `json:"sections"`
+}
+
+// ContentType implements Renderer.
+func (TeamsCard) ContentType() string { return "application/json" }
+
+// Render renders the report as a teams connector card with one section per report section and region.
+func (TeamsCard) Render(r report.Report) ([]byte, error) {
+	card := newTeamsCard(r, r.Heading())
+	card.Sections = append(card.Sections, teamsAlertSections(r)...)
+	card.Sections = append(card.Sections, teamsAccountSections(r)...)
+	for _, g := range groups(r, 0) {
+		card.Sections = append(card.Sections, teamsSection(g))
+	}
+	card.Sections = append(card.Sections, teamsProblemSections(r)...)
+	return json.Marshal(card)
+}
+
+// RenderMessages implements Splitter, sections that don't fit in a card are continued in further cards.
+func (TeamsCard) RenderMessages(r report.Report) ([][]byte, error) {
+	messages := make([][]byte, 0)
+	card := newTeamsCard(r, r.Heading())
+	size := 0
+	sections := append(teamsAlertSections(r), teamsAccountSections(r)...)
+	for _, g := range groups(r, maxTeamsFacts) {
+		sections = append(sections, teamsSection(g))
+	}
+	sections = append(sections, teamsProblemSections(r)...)
+	for _, section := range sections {
+		sectionBytes, err := json.Marshal(section)
+		if err != nil {
+			return nil, err
+		}
+		if len(card.Sections) > 0 && size+len(sectionBytes) > maxTeamsCardBytes {
+			payload, err := json.Marshal(card)
+			if err != nil {
+				return nil, err
+			}
+			messages = append(messages, payload)
+			card = newTeamsCard(r, continuedTitle(r.Heading()))
+			size = 0
+		}
+		card.Sections = append(card.Sections, section)
+		size += len(sectionBytes) + 1
+	}
+	payload, err := json.Marshal(card)
+	if err != nil {
+		return nil, err
+	}
+	return append(messages, payload), nil
+}
+
+// teamsColor returns the colour of the most severe metric of the report, alerts included.
+func teamsColor(r report.Report) string {
+	metrics := make([]report.Metric, 0)
+	for _, alert := range r.Alerts {
+		metric := alert.Metric
+		metric.Severity = alert.Severity
+		metrics = append(metrics, metric)
+	}
+	for _, section := range r.Sections {
+		metrics = append(metrics, section.Metrics...)
+	}
+	return severityColor(metrics)
+}
+
+func newTeamsCard(r report.Report, title string) TeamsCard {
+	return TeamsCard{
+		Type:       "MessageCard",
+		Context:    "https://schema.org/extensions",
+		Summary:    title,
+		Title:      title,
+		Text:       fmt.Sprintf("AWS usage from %s to %s", r.Period.Start.Format("2006-01-02"), r.Period.End.Format("2006-01-02")),
+		ThemeColor: teamsColor(r),
+		Sections:   make([]TeamsSection, 0),
+	}
+}
+
+// teamsAlertSections renders the alerts of the report as sections listing them.
+func teamsAlertSections(r report.Report) []TeamsSection {
+	sections := make([]TeamsSection, 0)
+	for i, text := range alertTexts(r, "- ", maxTeamsCardBytes/2, nil) {
+		title := alertsTitle
+		if i > 0 {
+			title = continuedTitle(title)
+		}
+		sections = append(sections, TeamsSection{ActivityTitle: title, Text: text})
+	}
+	return sections
+}
+
+// teamsAccountSections renders the account events of the report as sections listing them.
+func teamsAccountSections(r report.Report) []TeamsSection {
+	sections := make([]TeamsSection, 0)
+	for i, text := range accountTexts(r, "- ", maxTeamsCardBytes/2, nil) {
+		title := accountsTitle
+		if i > 0 {
+			title = continuedTitle(title)
+		}
+		sections = append(sections, TeamsSection{ActivityTitle: title, Text: text})
+	}
+	return sections
+}
+
+// teamsProblemSections renders the collection problems of the report as sections listing them.
+func teamsProblemSections(r report.Report) []TeamsSection {
+	sections := make([]TeamsSection, 0)
+	for i, text := range problemTexts(r, "- ", maxTeamsCardBytes/2, nil) {
+		title := problemsTitle
+		if i > 0 {
+			title = continuedTitle(title)
+		}
+		sections = append(sections, TeamsSection{ActivityTitle: title, Text: text})
+	}
+	return sections
+}
+
+func teamsSection(g group) TeamsSection {
+	section := TeamsSection{
+		ActivityTitle:    g.title,
+		ActivitySubtitle: g.subtitle(),
+	}
+	if len(g.metrics) == 0 {
+		section.Text = g.empty + "."
+	}
+	for _, metric := range g.metrics {
+		section.Facts = append(section.Facts, TeamsFact{Name: metric.Label(), Value: format.Value(metric)})
+	}
+	return section
+}
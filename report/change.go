@@ -0,0 +1,80 @@
+package report
+
+import (
+	"math"
+	"time"
+)
+
+// The earlier values a metric is compared with.
+const (
+	SincePrevious = "previous"
+	SinceLastWeek = "last_week"
+	// SinceLastMonth compares a metric of the month with the same metric of the month before.
+	SinceLastMonth = "last_month"
+)
+
+// Change holds the value of a metric in an earlier report, or in the month before.
+type Change struct {
+	Since string    `json:"since"`
+	Value float64   `json:"value"`
+	At    time.Time `json:"at"`
+}
+
+// Delta returns how much the metric changed since the earlier report.
+func (m Metric) Delta(c Change) float64 {
+	return m.Value - c.Value
+}
+
+// PercentChange returns how much the metric changed since the earlier report in percent of its earlier value,
+// which is infinite if it changed from 0.
+func (m Metric) PercentChange(c Change) float64 {
+	delta := m.Delta(c)
+	if delta == 0 {
+		return 0
+	}
+	if c.Value == 0 {
+		return math.Inf(1)
+	}
+	return math.Abs(delta / c.Value * 100)
+}
+
+// Severity ranks how much attention a metric needs.
+type Severity string
+
+// The severities, from the least to the most severe.
+const (
+	Normal   Severity = ""
+	Warning  Severity = "warning"
+	Critical Severity = "critical"
+)
+
+// rank orders the severities.
+var rank = map[Severity]int{Normal: 0, Warning: 1, Critical: 2}
+
+// Above reports whether s is more severe than other.
+func (s Severity) Above(other Severity) bool {
+	return rank[s] > rank[other]
+}
+
+// Alert records a metric breaking a rule.
+type Alert struct {
+	Rule     string   `json:"rule"`
+	Severity Severity `json:"severity"`
+	// Condition is "above" or "below" the threshold.
+	Condition string  `json:"condition"`
+	Threshold float64 `json:"threshold"`
+	Metric    Metric  `json:"metric"`
+	// Detail tells more about the alert, if anything.
+	Detail string `json:"detail,omitempty"`
+}
+
+// Severity returns the most severe of the section's metrics.
+func (s Section) Severity() Severity {
+	severity := Normal
+	for _, metric := range s.Metrics {
+		if metric.Severity.Above(severity) {
+			severity = metric.Severity
+		}
+	}
+	return severity
+}
@@ -0,0 +1,174 @@
+// Package report defines the structured usage report that collectors produce and jobs render.
+package report
+
+import (
+	"sort"
+	"strings"
+	"time"
+)
+
+// Unit defines the unit of a metric value.
+type Unit string
+
+// The units of metric values.
+const (
+	Count          Unit = "Count"
+	CountPerDay    Unit = "Count/Day"
+	CountPerSecond Unit = "Count/Second"
+	Bytes          Unit = "Bytes"
+	BytesPerDay    Unit = "Bytes/Day"
+	BytesPerSecond Unit = "Bytes/Second"
+	Percent        Unit = "Percent"
+	Days           Unit = "Days"
+	USD            Unit = "USD"
+)
+
+// Period defines the time window a report or metric covers.
+type Period struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+}
+
+// MonthOf returns the calendar month period that contains t.
+func MonthOf(t time.Time) Period {
+	year, month, _ := t.Date()
+	start := time.Date(year, month, 1, 0, 0, 0, 0, t.Location())
+	return Period{
+		Start: start,
+		End:   start.AddDate(0, 1, 0).Add(-time.Second),
+	}
+}
+
+// Previous returns the calendar month period before p.
+func (p Period) Previous() Period {
+	return MonthOf(p.Start.AddDate(0, -1, 0))
+}
+
+// Metric is a single measured value.
+// Account is the alias or ID of the account it was measured in, empty when a single account is watched.
+type Metric struct {
+	Account    string            `json:"account,omitempty"`
+	Service    string            `json:"service"`
+	Region     string            `json:"region"`
+	Name       string            `json:"name"`
+	Value      float64           `json:"value"`
+	Unit       Unit              `json:"unit"`
+	Dimensions map[string]string `json:"dimensions,omitempty"`
+	Period     Period            `json:"period"`
+	// Band is the range the value is expected in, if it is an estimate.
+	Band *Band `json:"band,omitempty"`
+	// Share is the part in percent the value makes of a total, if it is part of one.
+	Share float64 `json:"share,omitempty"`
+	// ToDate is set on the values accumulated since the start of the period, e.g. the month to date charges,
+	// which are only compared with the values of the same period.
+	ToDate bool `json:"to_date,omitempty"`
+	// Changes compare the value with earlier reports, if any were kept.
+	Changes  []Change `json:"changes,omitempty"`
+	Severity Severity `json:"severity,omitempty"`
+}
+
+// Band is the range an estimated value is expected in.
+type Band struct {
+	Low  float64 `json:"low"`
+	High float64 `json:"high"`
+}
+
+// Label returns the title of the metric, which is its dimension values if it has any and its name otherwise.
+func (m Metric) Label() string {
+	if len(m.Dimensions) == 0 {
+		return m.Name
+	}
+	keys := make([]string, 0, len(m.Dimensions))
+	for key := range m.Dimensions {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	values := make([]string, 0, len(keys))
+	for _, key := range keys {
+		values = append(values, m.Dimensions[key])
+	}
+	return strings.Join(values, " / ")
+}
+
+// Section holds the metrics gathered by one collector.
+// A partial section lacks the metrics its collector couldn't gather before timing out,
+// a failed one those its collector met problems gathering.
+// Account is the alias or ID of the account the metrics were gathered in, empty when a single account is watched.
+type Section struct {
+	Account string   `json:"account,omitempty"`
+	Service string   `json:"service"`
+	Title   string   `json:"title"`
+	Global  bool     `json:"global"`
+	Partial bool     `json:"partial,omitempty"`
+	Failed  bool     `json:"failed,omitempty"`
+	Metrics []Metric `json:"metrics"`
+}
+
+// RegionMetrics holds the metrics of a section gathered in one region.
+type RegionMetrics struct {
+	Region  string
+	Metrics []Metric
+}
+
+// ByRegion groups the metrics of the section by region, in the order the regions first appear.
+func (s Section) ByRegion() []RegionMetrics {
+	groups := make([]RegionMetrics, 0)
+	index := make(map[string]int)
+	for _, metric := range s.Metrics {
+		i, ok := index[metric.Region]
+		if !ok {
+			i = len(groups)
+			index[metric.Region] = i
+			groups = append(groups, RegionMetrics{Region: metric.Region})
+		}
+		groups[i].Metrics = append(groups[i].Metrics, metric)
+	}
+	return groups
+}
+
+// Problem records why part of the usage couldn't be collected.
+type Problem struct {
+	Account string `json:"account,omitempty"`
+	Service string `json:"service"`
+	Region  string `json:"region"`
+	API     string `json:"api"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// The events of the member accounts of an organization.
+const (
+	Joined    = "joined"
+	Suspended = "suspended"
+	Left      = "left"
+)
+
+// AccountEvent records a member account joining, being suspended from or leaving the organization.
+type AccountEvent struct {
+	Account string `json:"account"`
+	ID      string `json:"id"`
+	Event   string `json:"event"`
+}
+
+// DefaultTitle is the title of a report that doesn't set one.
+const DefaultTitle = "AWS Usage Report"
+
+// Report is the usage report of one run.
+type Report struct {
+	Title       string    `json:"title,omitempty"`
+	Period      Period    `json:"period"`
+	GeneratedAt time.Time `json:"generated_at"`
+	Alerts      []Alert   `json:"alerts,omitempty"`
+	// AccountEvents are the accounts that joined or left the organization since the previous report.
+	AccountEvents []AccountEvent `json:"account_events,omitempty"`
+	Sections      []Section      `json:"sections"`
+	Problems      []Problem      `json:"problems,omitempty"`
+}
+
+// Heading returns the title of the report, or the default title if it has none.
+func (r Report) Heading() string {
+	if r.Title == "" {
+		return DefaultTitle
+	}
+	return r.Title
+}
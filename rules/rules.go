@@ -0,0 +1,71 @@
+// Package rules evaluates threshold rules against the metrics of a report, e.g. "RDS CPU above 80%".
+package rules
+
+import (
+	"github.com/WUMUXIAN/aws-slack-bot/report"
+)
+
+// The conditions of a rule.
+const (
+	Above = "above"
+	Below = "below"
+)
+
+// Rule is broken by the metrics it selects whose value is above or below its threshold.
+// The account, region and label select any metric when empty.
+type Rule struct {
+	Name      string
+	Service   string
+	Metric    string
+	Account   string
+	Region    string
+	Label     string
+	Condition string
+	Threshold float64
+	Severity  report.Severity
+}
+
+// Selects reports whether the rule applies to the metric.
+func (o Rule) Selects(m report.Metric) bool {
+	return o.Service == m.Service &&
+		o.Metric == m.Name &&
+		(o.Account == "" || o.Account == m.Account) &&
+		(o.Region == "" || o.Region == m.Region) &&
+		(o.Label == "" || o.Label == m.Label())
+}
+
+// Broken reports whether the metric breaks the rule.
+func (o Rule) Broken(m report.Metric) bool {
+	if !o.Selects(m) {
+		return false
+	}
+	if o.Condition == Below {
+		return m.Value < o.Threshold
+	}
+	return m.Value > o.Threshold
+}
+
+// Evaluate checks the report's metrics against the rules, raising the severity of the metrics
+// that break a rule and listing them in the report's alerts, in the order of the rules.
+func Evaluate(rules []Rule, r *report.Report) {
+	for _, rule := range rules {
+		for i := range r.Sections {
+			for j := range r.Sections[i].Metrics {
+				metric := &r.Sections[i].Metrics[j]
+				if !rule.Broken(*metric) {
+					continue
+				}
+				if rule.Severity.Above(metric.Severity) {
+					metric.Severity = rule.Severity
+				}
+				r.Alerts = append(r.Alerts, report.Alert{
+					Rule:      rule.Name,
+					Severity:  rule.Severity,
+					Condition: rule.Condition,
+					Threshold: rule.Threshold,
+					Metric:    *metric,
+				})
+			}
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package rules
+
+import (
+	"testing"
+
+	"github.com/WUMUXIAN/aws-slack-bot/report"
+)
+
+func TestBroken(t *testing.T) {
+	cpu := report.Metric{Service: "rds", Region: "eu-west-1", Name: "CPU", Value: 85, Unit: report.Percent, Dimensions: map[string]string{"DBInstanceIdentifier": "db-1"}}
+	tests := []struct {
+		name   string
+		rule   Rule
+		broken bool
+	}{
+		{"above", Rule{Service: "rds", Metric: "CPU", Condition: Above, Threshold: 80}, true},
+		{"not above", Rule{Service: "rds", Metric: "CPU", Condition: Above, Threshold: 90}, false},
+		{"at the threshold", Rule{Service: "rds", Metric: "CPU", Condition: Above, Threshold: 85}, false},
+		{"below", Rule{Service: "rds", Metric: "CPU", Condition: Below, Threshold: 90}, true},
+		{"not below", Rule{Service: "rds", Metric: "CPU", Condition: Below, Threshold: 80}, false},
+		{"region", Rule{Service: "rds", Metric: "CPU", Region: "eu-west-1", Condition: Above, Threshold: 80}, true},
+		{"other region", Rule{Service: "rds", Metric: "CPU", Region: "us-east-1", Condition: Above, Threshold: 80}, false},
+		{"label", Rule{Service: "rds", Metric: "CPU", Label: "db-1", Condition: Above, Threshold: 80}, true},
+		{"other label", Rule{Service: "rds", Metric: "CPU", Label: "db-2", Condition: Above, Threshold: 80}, false},
+		{"other service", Rule{Service: "ec2", Metric: "CPU", Condition: Above, Threshold: 80}, false},
+		{"other metric", Rule{Service: "rds", Metric: "Connections", Condition: Above, Threshold: 80}, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if broken := test.rule.Broken(cpu); broken != test.broken {
+				t.Errorf("got broken %t, want %t", broken, test.broken)
+			}
+		})
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	r := report.Report{Sections: []report.Section{
+		{Service: "billing", Global: true, Metrics: []report.Metric{
+			{Service: "billing", Region: "us-east-1", Name: "Accumulated This Month", Value: 612, Unit: report.USD},
+		}},
+		{Service: "rds", Metrics: []report.Metric{
+			{Service: "rds", Region: "eu-west-1", Name: "CPU", Value: 85, Unit: report.Percent, Severity: report.Critical},
+			{Service: "rds", Region: "eu-west-1", Name: "CPU", Value: 40, Unit: report.Percent},
+		}},
+	}}
+	Evaluate([]Rule{
+		{Name: "RDS CPU", Service: "rds", Metric: "CPU", Condition: Above, Threshold: 80, Severity: report.Warning},
+		{Name: "Charges", Service: "billing", Metric: "Accumulated This Month", Condition: Above, Threshold: 500, Severity: report.Critical},
+		{Name: "Low charges", Service: "billing", Metric: "Accumulated This Month", Condition: Below, Threshold: 100, Severity: report.Warning},
+	}, &r)
+
+	// The alerts come in the order of the rules.
+	if len(r.Alerts) != 2 {
+		t.Fatalf("got %d alerts %v, want 2", len(r.Alerts), r.Alerts)
+	}
+	if r.Alerts[0].Rule != "RDS CPU" || r.Alerts[0].Metric.Value != 85 || r.Alerts[0].Severity != report.Warning {
+		t.Errorf("got first alert %+v, want RDS CPU at 85 as a warning", r.Alerts[0])
+	}
+	if r.Alerts[1].Rule != "Charges" || r.Alerts[1].Condition != Above || r.Alerts[1].Threshold != 500 {
+		t.Errorf("got second alert %+v, want Charges above 500", r.Alerts[1])
+	}
+	// The broken metrics are raised to the severity of the rule, never lowered.
+	if severity := r.Sections[0].Metrics[0].Severity; severity != report.Critical {
+		t.Errorf("got charges severity %q, want critical", severity)
+	}
+	if severity := r.Sections[1].Metrics[0].Severity; severity != report.Critical {
+		t.Errorf("got broken CPU severity %q, want it to stay critical", severity)
+	}
+	if severity := r.Sections[1].Metrics[1].Severity; severity != report.Normal {
+		t.Errorf("got unbroken CPU severity %q, want normal", severity)
+	}
+}
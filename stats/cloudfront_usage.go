@@ -2,25 +2,35 @@
 package stats
 
 import (
-	"fmt"
-	"time"
+	"context"
 
+	"github.com/WUMUXIAN/aws-slack-bot/report"
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/cloudwatch"
 )
 
-// GetCloudFrontUsage gets cloudfront usage for given session within specified period of time.
-func GetCloudFrontUsage(sess *session.Session, startTime, endTime time.Time) (cloudFrontUsage map[string]string) {
-	cloudFrontUsage = make(map[string]string)
+type cloudFrontCollector struct{}
+
+func (cloudFrontCollector) Name() string       { return "cloudfront" }
+func (cloudFrontCollector) Title() string      { return "CloudFront Usage" }
+func (cloudFrontCollector) Scope() Scope       { return RegionalScope }
+func (cloudFrontCollector) ToDate() bool       { return false }
+func (cloudFrontCollector) BillingMonth() bool { return false }
+
+// Collect gets cloudfront usage for given session within specified period of time.
+func (cloudFrontCollector) Collect(ctx context.Context, sess *session.Session, period report.Period) ([]report.Metric, error) {
+	startTime, endTime := period.Start, period.End
+	cloudFrontUsage := make(metrics, 0)
+	var errs Errors
 	svc := cloudwatch.New(sess)
 
 	interestedMetrics := make([]*cloudwatch.Metric, 0)
-	respListMetrics, err := svc.ListMetrics(&cloudwatch.ListMetricsInput{
+	respListMetrics, err := svc.ListMetricsWithContext(ctx, &cloudwatch.ListMetricsInput{
 		Namespace: aws.String("AWS/CloudFront"),
 	})
 	if err != nil {
-		fmt.Println(err.Error())
+		errs.add("cloudwatch:ListMetrics", err)
 	} else {
 		for _, metrics := range respListMetrics.Metrics {
 			if aws.StringValue(metrics.MetricName) == "Requests" || aws.StringValue(metrics.MetricName) == "BytesDownloaded" {
@@ -32,8 +42,13 @@ func GetCloudFrontUsage(sess *session.Session, startTime, endTime time.Time) (cl
 	requests := float64(0)
 	downloads := float64(0)
 	for _, metrics := range interestedMetrics {
+		// Stop once timed out, the remaining metrics would fail the same way.
+		if ctx.Err() != nil {
+			break
+		}
 		if aws.StringValue(metrics.MetricName) == "Requests" {
-			stats := getMetricsStatistics(svc, startTime, endTime, metrics.Namespace, metrics.MetricName, "Sum", metrics.Dimensions)
+			stats, err := getMetricsStatistics(ctx, svc, startTime, endTime, metrics.Namespace, metrics.MetricName, "Sum", metrics.Dimensions)
+			errs.add("cloudwatch:GetMetricStatistics", err)
 			reqs := float64(0)
 			for i := 1; i < len(stats); i++ {
 				reqs += stats[i]
@@ -42,7 +57,8 @@ func GetCloudFrontUsage(sess *session.Session, startTime, endTime time.Time) (cl
 				requests += reqs / float64(len(stats)-1)
 			}
 		} else {
-			stats := getMetricsStatistics(svc, startTime, endTime, metrics.Namespace, metrics.MetricName, "Sum", metrics.Dimensions)
+			stats, err := getMetricsStatistics(ctx, svc, startTime, endTime, metrics.Namespace, metrics.MetricName, "Sum", metrics.Dimensions)
+			errs.add("cloudwatch:GetMetricStatistics", err)
 			dls := float64(0)
 			for i := 1; i < len(stats); i++ {
 				dls += stats[i]
@@ -53,11 +69,11 @@ func GetCloudFrontUsage(sess *session.Session, startTime, endTime time.Time) (cl
 		}
 	}
 	if requests > 0 {
-		cloudFrontUsage["Request Count"] = fmt.Sprintf("%0.2f/Day", requests)
+		cloudFrontUsage.add("Request Count", requests, report.CountPerDay)
 	}
 	if downloads > 0 {
-		cloudFrontUsage["Downloaded Size"] = fmt.Sprintf("%s/Day", formatStorage(downloads))
+		cloudFrontUsage.add("Downloaded Size", downloads, report.BytesPerDay)
 	}
 
-	return cloudFrontUsage
+	return cloudFrontUsage, errs.err()
 }
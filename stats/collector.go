@@ -0,0 +1,49 @@
+package stats
+
+import (
+	"context"
+
+	"github.com/WUMUXIAN/aws-slack-bot/report"
+	"github.com/aws/aws-sdk-go/aws/session"
+)
+
+// GlobalRegion is the region global collectors are run against.
+const GlobalRegion = "us-east-1"
+
+// Scope defines how often a collector is run for a report.
+type Scope int
+
+const (
+	// RegionalScope collectors are run once for every watched region.
+	RegionalScope Scope = iota
+	// GlobalScope collectors are run once per report against GlobalRegion, e.g. those of the AWS/Billing metrics
+	// and of Cost Explorer, which report the whole account from us-east-1 only.
+	GlobalScope
+)
+
+// Collector gathers the usage of one service.
+type Collector interface {
+	// Name is the unique name used to enable the collector in configuration.
+	Name() string
+	// Title is the heading of the collector's section in the report.
+	Title() string
+	// Scope tells whether the collector is run per region or once per report.
+	Scope() Scope
+	// ToDate tells whether the metrics are accumulated since the start of their period, e.g. the month to date charges,
+	// rather than measured when collected.
+	ToDate() bool
+	// BillingMonth tells whether the period is the month in UTC that AWS bills by,
+	// rather than the month in the timezone of the report.
+	BillingMonth() bool
+	// Collect gathers the usage for given session within specified period of time.
+	// The caller fills in the service, region and, when left empty, the period of the returned metrics.
+	// Failed API calls are returned as Errors along with the metrics the other calls gathered.
+	Collect(ctx context.Context, sess *session.Session, period report.Period) ([]report.Metric, error)
+}
+
+// metrics accumulates the metrics gathered by a collector.
+type metrics []report.Metric
+
+func (m *metrics) add(name string, value float64, unit report.Unit) {
+	*m = append(*m, report.Metric{Name: name, Value: value, Unit: unit})
+}
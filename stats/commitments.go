@@ -0,0 +1,172 @@
+package stats
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/WUMUXIAN/aws-slack-bot/report"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/costexplorer"
+)
+
+type commitmentsCollector struct {
+	expiringDays int
+}
+
+// NewCommitmentsCollector creates the collector reporting the utilization and coverage of the reservations
+// and Savings Plans, and the reservations expiring within given number of days.
+func NewCommitmentsCollector(expiringDays int) (Collector, error) {
+	if expiringDays < 1 {
+		return nil, fmt.Errorf("the reservations must be listed at least a day before they expire")
+	}
+	return commitmentsCollector{expiringDays: expiringDays}, nil
+}
+
+func (commitmentsCollector) Name() string       { return "commitments" }
+func (commitmentsCollector) Title() string      { return "Reservations and Savings Plans" }
+func (commitmentsCollector) Scope() Scope       { return GlobalScope }
+func (commitmentsCollector) ToDate() bool       { return true }
+func (commitmentsCollector) BillingMonth() bool { return true }
+
+// Collect gets the utilization and coverage of the reservations and Savings Plans in the given period so far,
+// the on-demand cost they could have covered, and the reservations expiring soon with the days they have left.
+// The coverage is only reported for the commitments the account has.
+func (o commitmentsCollector) Collect(ctx context.Context, sess *session.Session, period report.Period) ([]report.Metric, error) {
+	svc := costexplorer.New(sess)
+	start, end := costExplorerPeriod(period, time.Now())
+	interval := &costexplorer.DateInterval{Start: aws.String(start), End: aws.String(end)}
+	commitments := make(metrics, 0)
+	var errs Errors
+
+	utilization, expiring, err := getReservationUtilization(ctx, svc, interval, time.Now(), o.expiringDays)
+	if err != nil && !unavailable(err) {
+		errs.add("ce:GetReservationUtilization", err)
+	} else if utilization != nil {
+		commitments.add("RI Utilization", *utilization, report.Percent)
+		coverage, err := svc.GetReservationCoverageWithContext(ctx, &costexplorer.GetReservationCoverageInput{TimePeriod: interval})
+		if err != nil && !unavailable(err) {
+			errs.add("ce:GetReservationCoverage", err)
+		} else if err == nil && coverage.Total != nil && coverage.Total.CoverageHours != nil {
+			commitments.add("RI Coverage", amount(coverage.Total.CoverageHours.CoverageHoursPercentage), report.Percent)
+			if coverage.Total.CoverageCost != nil {
+				commitments.add("On-Demand Cost Not Covered by RIs", amount(coverage.Total.CoverageCost.OnDemandCost), report.USD)
+			}
+		}
+	}
+
+	planUtilization, err := svc.GetSavingsPlansUtilizationWithContext(ctx, &costexplorer.GetSavingsPlansUtilizationInput{TimePeriod: interval})
+	if err != nil && !unavailable(err) {
+		errs.add("ce:GetSavingsPlansUtilization", err)
+	} else if err == nil && planUtilization.Total != nil && planUtilization.Total.Utilization != nil {
+		commitments.add("Savings Plans Utilization", amount(planUtilization.Total.Utilization.UtilizationPercentage), report.Percent)
+		coverage, err := getSavingsPlansCoverage(ctx, svc, interval)
+		if err != nil && !unavailable(err) {
+			errs.add("ce:GetSavingsPlansCoverage", err)
+		} else if coverage.total > 0 {
+			commitments.add("Savings Plans Coverage", coverage.covered/coverage.total*100, report.Percent)
+			commitments.add("On-Demand Cost Not Covered by Savings Plans", coverage.onDemand, report.USD)
+		}
+	}
+
+	return append(commitments, expiring...), errs.err()
+}
+
+// getReservationUtilization gets the utilization in percent of the reservations in the interval, nil if there is none,
+// and the reservations expiring within given number of days after now, the soonest first.
+func getReservationUtilization(ctx context.Context, svc *costexplorer.CostExplorer, interval *costexplorer.DateInterval, now time.Time, days int) (*float64, []report.Metric, error) {
+	// The utilization of every reservation tells when it ends.
+	input := &costexplorer.GetReservationUtilizationInput{
+		TimePeriod: interval,
+		GroupBy: []*costexplorer.GroupDefinition{{
+			Type: aws.String(costexplorer.GroupDefinitionTypeDimension),
+			Key:  aws.String(costexplorer.DimensionSubscriptionId),
+		}},
+	}
+	var utilization *float64
+	expiring := make([]report.Metric, 0)
+	for {
+		resp, err := svc.GetReservationUtilizationWithContext(ctx, input)
+		if err != nil {
+			return nil, nil, err
+		}
+		if resp.Total != nil && aws.StringValue(resp.Total.PurchasedHours) != "" {
+			utilization = aws.Float64(amount(resp.Total.UtilizationPercentage))
+		}
+		for _, byTime := range resp.UtilizationsByTime {
+			for _, group := range byTime.Groups {
+				ends, err := time.Parse(time.RFC3339, aws.StringValue(group.Attributes["endDateTime"]))
+				if err != nil || ends.Before(now) || ends.After(now.AddDate(0, 0, days)) {
+					continue
+				}
+				expiring = append(expiring, report.Metric{
+					Name:  "RI Expiring In",
+					Value: math.Ceil(ends.Sub(now).Hours() / 24),
+					Unit:  report.Days,
+					Dimensions: map[string]string{
+						"Reservation":  aws.StringValue(group.Value),
+						"InstanceType": aws.StringValue(group.Attributes["instanceType"]),
+						"Region":       aws.StringValue(group.Attributes["region"]),
+					},
+					Severity: report.Warning,
+				})
+			}
+		}
+		if aws.StringValue(resp.NextPageToken) == "" {
+			break
+		}
+		input.NextPageToken = resp.NextPageToken
+	}
+	sort.SliceStable(expiring, func(i, j int) bool {
+		return expiring[i].Value < expiring[j].Value
+	})
+	return utilization, expiring, nil
+}
+
+// savingsPlansCoverage adds up the coverage of the Savings Plans over an interval.
+type savingsPlansCoverage struct {
+	covered  float64
+	onDemand float64
+	total    float64
+}
+
+// getSavingsPlansCoverage gets the spend covered by the Savings Plans in the interval, following all the pages.
+func getSavingsPlansCoverage(ctx context.Context, svc *costexplorer.CostExplorer, interval *costexplorer.DateInterval) (savingsPlansCoverage, error) {
+	var coverage savingsPlansCoverage
+	input := &costexplorer.GetSavingsPlansCoverageInput{TimePeriod: interval, Granularity: aws.String(costexplorer.GranularityMonthly)}
+	err := svc.GetSavingsPlansCoveragePagesWithContext(ctx, input, func(page *costexplorer.GetSavingsPlansCoverageOutput, lastPage bool) bool {
+		for _, c := range page.SavingsPlansCoverages {
+			if c.Coverage == nil {
+				continue
+			}
+			coverage.covered += amount(c.Coverage.SpendCoveredBySavingsPlans)
+			coverage.onDemand += amount(c.Coverage.OnDemandCost)
+			coverage.total += amount(c.Coverage.TotalCost)
+		}
+		return true
+	})
+	if err != nil {
+		return savingsPlansCoverage{}, err
+	}
+	return coverage, nil
+}
+
+// unavailable tells whether Cost Explorer has no data for the call, e.g. as the account has no Savings Plans.
+func unavailable(err error) bool {
+	aerr, ok := err.(awserr.Error)
+	return ok && aerr.Code() == costexplorer.ErrCodeDataUnavailableException
+}
+
+// amount parses an amount of Cost Explorer, 0 if it is missing.
+func amount(value *string) float64 {
+	parsed, err := strconv.ParseFloat(aws.StringValue(value), 64)
+	if err != nil {
+		return 0
+	}
+	return parsed
+}
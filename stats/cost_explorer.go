@@ -0,0 +1,226 @@
+package stats
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/WUMUXIAN/aws-slack-bot/report"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/costexplorer"
+)
+
+// The costs the Cost Explorer collector reports.
+const (
+	UnblendedCost = "unblended"
+	AmortizedCost = "amortized"
+)
+
+// The groupings of the costs the Cost Explorer collector reports.
+const (
+	ByDay           = "day"
+	ByService       = "service"
+	ByLinkedAccount = "linked_account"
+	ByUsageType     = "usage_type"
+)
+
+// costMetrics are the Cost Explorer metrics of the costs, with the names they are reported by.
+var costMetrics = map[string]struct{ metric, name string }{
+	UnblendedCost: {"UnblendedCost", "Unblended Cost"},
+	AmortizedCost: {"AmortizedCost", "Amortized Cost"},
+}
+
+// costGroupings are the Cost Explorer dimensions of the groupings, with the dimension and name they are reported by,
+// and the name the groups beyond the top ones are added up under. The days are grouped by granularity instead.
+var costGroupings = map[string]struct{ dimension, key, name, others string }{
+	ByDay:           {"", "Day", "by Day", ""},
+	ByService:       {costexplorer.DimensionService, "Service", "by Service", "Other services"},
+	ByLinkedAccount: {costexplorer.DimensionLinkedAccount, "LinkedAccount", "by Linked Account", "Other accounts"},
+	ByUsageType:     {costexplorer.DimensionUsageType, "UsageType", "by Usage Type", "Other usage types"},
+}
+
+// costExplorerDate is the layout of the dates of Cost Explorer.
+const costExplorerDate = "2006-01-02"
+
+type costExplorerCollector struct {
+	cost    string
+	groupBy []string
+}
+
+// NewCostExplorerCollector creates the collector reporting the month to date cost of given type,
+// unblended or amortized, in total and grouped by each of the given groupings.
+func NewCostExplorerCollector(cost string, groupBy []string) (Collector, error) {
+	if _, ok := costMetrics[cost]; !ok {
+		return nil, fmt.Errorf("unknown cost %q, available costs are %s, %s", cost, UnblendedCost, AmortizedCost)
+	}
+	for _, grouping := range groupBy {
+		if _, ok := costGroupings[grouping]; !ok {
+			return nil, fmt.Errorf("unknown grouping %q, available groupings are %s", grouping,
+				strings.Join([]string{ByDay, ByService, ByLinkedAccount, ByUsageType}, ", "))
+		}
+	}
+	return costExplorerCollector{cost: cost, groupBy: groupBy}, nil
+}
+
+func (costExplorerCollector) Name() string       { return "costexplorer" }
+func (costExplorerCollector) Title() string      { return "Cost Explorer" }
+func (costExplorerCollector) Scope() Scope       { return GlobalScope }
+func (costExplorerCollector) ToDate() bool       { return true }
+func (costExplorerCollector) BillingMonth() bool { return true }
+
+// Collect gets the month to date cost of the given period from Cost Explorer, in total and by each grouping.
+// The groups that cost the most are listed with their share of the total, the others being added up.
+func (o costExplorerCollector) Collect(ctx context.Context, sess *session.Session, period report.Period) ([]report.Metric, error) {
+	svc := costexplorer.New(sess)
+	cost := costMetrics[o.cost]
+	start, end := costExplorerPeriod(period, time.Now())
+	var errs Errors
+
+	results, err := getCostAndUsage(ctx, svc, start, end, costexplorer.GranularityMonthly, cost.metric, nil)
+	if err != nil {
+		errs.add("ce:GetCostAndUsage", err)
+		return nil, errs.err()
+	}
+	total := 0.0
+	for _, result := range results {
+		total += costAmount(result.Total, cost.metric)
+	}
+	costs := metrics{{Name: cost.name + " This Month", Value: total, Unit: report.USD}}
+
+	for _, name := range o.groupBy {
+		grouping := costGroupings[name]
+		if name == ByDay {
+			results, err := getCostAndUsage(ctx, svc, start, end, costexplorer.GranularityDaily, cost.metric, nil)
+			if err != nil {
+				errs.add("ce:GetCostAndUsage", err)
+				continue
+			}
+			costs = append(costs, dailyCosts(results, cost.metric, cost.name+" "+grouping.name, period.Start.Location())...)
+			continue
+		}
+
+		results, err := getCostAndUsage(ctx, svc, start, end, costexplorer.GranularityMonthly, cost.metric,
+			&costexplorer.GroupDefinition{Type: aws.String(costexplorer.GroupDefinitionTypeDimension), Key: aws.String(grouping.dimension)})
+		if err != nil {
+			errs.add("ce:GetCostAndUsage", err)
+			continue
+		}
+		groups := make(map[string]float64)
+		for _, result := range results {
+			for _, group := range result.Groups {
+				if len(group.Keys) > 0 {
+					groups[aws.StringValue(group.Keys[0])] += costAmount(group.Metrics, cost.metric)
+				}
+			}
+		}
+		costs = append(costs, groupCosts(groups, total, cost.name+" "+grouping.name, grouping.key, grouping.others)...)
+	}
+	return costs, errs.err()
+}
+
+// costExplorerPeriod returns the first day of the period and the day after the last one elapsed at now,
+// Cost Explorer taking the end of a time period as exclusive.
+func costExplorerPeriod(period report.Period, now time.Time) (string, string) {
+	end := now.In(period.Start.Location()).AddDate(0, 0, 1)
+	if last := period.End.AddDate(0, 0, 1); end.After(last) {
+		end = last
+	}
+	return period.Start.Format(costExplorerDate), end.Format(costExplorerDate)
+}
+
+// getCostAndUsage gets the cost of given metric between the start and end dates at given granularity,
+// grouped as defined if at all, following all the pages of the results.
+func getCostAndUsage(ctx context.Context, svc *costexplorer.CostExplorer, start, end, granularity, metric string, groupBy *costexplorer.GroupDefinition) ([]*costexplorer.ResultByTime, error) {
+	input := &costexplorer.GetCostAndUsageInput{
+		TimePeriod:  &costexplorer.DateInterval{Start: aws.String(start), End: aws.String(end)},
+		Granularity: aws.String(granularity),
+		Metrics:     []*string{aws.String(metric)},
+	}
+	if groupBy != nil {
+		input.GroupBy = []*costexplorer.GroupDefinition{groupBy}
+	}
+	results := make([]*costexplorer.ResultByTime, 0)
+	for {
+		resp, err := svc.GetCostAndUsageWithContext(ctx, input)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, resp.ResultsByTime...)
+		if aws.StringValue(resp.NextPageToken) == "" {
+			return results, nil
+		}
+		input.NextPageToken = resp.NextPageToken
+	}
+}
+
+// dailyCosts returns the cost of every day of the results, the latest first as the other datapoints.
+func dailyCosts(results []*costexplorer.ResultByTime, metric, name string, location *time.Location) []report.Metric {
+	days := make([]report.Metric, 0, len(results))
+	for i := len(results) - 1; i >= 0; i-- {
+		day := aws.StringValue(results[i].TimePeriod.Start)
+		start, err := time.ParseInLocation(costExplorerDate, day, location)
+		if err != nil {
+			continue
+		}
+		days = append(days, report.Metric{
+			Name:       name,
+			Value:      costAmount(results[i].Total, metric),
+			Unit:       report.USD,
+			Dimensions: map[string]string{"Day": day},
+			Period:     report.Period{Start: start, End: start.AddDate(0, 0, 1).Add(-time.Second)},
+		})
+	}
+	return days
+}
+
+// groupCosts returns the cost of the groups that cost the most with their share of the total,
+// the others being added up under given name.
+func groupCosts(groups map[string]float64, total float64, name, key, others string) []report.Metric {
+	keys := make([]string, 0, len(groups))
+	for group, cost := range groups {
+		if cost > 0 {
+			keys = append(keys, group)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if groups[keys[i]] != groups[keys[j]] {
+			return groups[keys[i]] > groups[keys[j]]
+		}
+		return keys[i] < keys[j]
+	})
+
+	rest := 0.0
+	if len(keys) > topServices {
+		for _, group := range keys[topServices:] {
+			rest += groups[group]
+		}
+		keys = keys[:topServices]
+	}
+	costs := make([]report.Metric, 0, len(keys)+1)
+	add := func(group string, cost float64) {
+		metric := report.Metric{Name: name, Value: cost, Unit: report.USD, Dimensions: map[string]string{key: group}}
+		if total > 0 {
+			metric.Share = cost / total * 100
+		}
+		costs = append(costs, metric)
+	}
+	for _, group := range keys {
+		add(group, groups[group])
+	}
+	if rest > 0 {
+		add(others, rest)
+	}
+	return costs
+}
+
+// costAmount returns the amount of the metric among the values, 0 if it is missing.
+func costAmount(values map[string]*costexplorer.MetricValue, metric string) float64 {
+	value, ok := values[metric]
+	if !ok || value == nil {
+		return 0
+	}
+	return amount(value.Amount)
+}
@@ -1,23 +1,31 @@
 package stats
 
 import (
-	"fmt"
-	"os"
-	"strconv"
+	"context"
 
+	"github.com/WUMUXIAN/aws-slack-bot/report"
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/ec2"
 	"github.com/aws/aws-sdk-go/service/elb"
 )
 
-// GetEC2Usage gets EC2 usage for given session.
-func GetEC2Usage(sess *session.Session) (ec2Usage map[string]string) {
-	ec2Usage = make(map[string]string)
+type ec2Collector struct{}
+
+func (ec2Collector) Name() string       { return "ec2" }
+func (ec2Collector) Title() string      { return "EC2 Usage" }
+func (ec2Collector) Scope() Scope       { return RegionalScope }
+func (ec2Collector) ToDate() bool       { return false }
+func (ec2Collector) BillingMonth() bool { return false }
+
+// Collect gets EC2 usage for given session.
+func (ec2Collector) Collect(ctx context.Context, sess *session.Session, period report.Period) ([]report.Metric, error) {
+	ec2Usage := make(metrics, 0)
+	var errs Errors
 
 	svc := ec2.New(sess)
 	// Get running instances
-	respDescribeInstances, err := svc.DescribeInstances(&ec2.DescribeInstancesInput{
+	respDescribeInstances, err := svc.DescribeInstancesWithContext(ctx, &ec2.DescribeInstancesInput{
 		Filters: []*ec2.Filter{
 			{
 				Name: aws.String("instance-state-name"),
@@ -28,75 +36,84 @@ func GetEC2Usage(sess *session.Session) (ec2Usage map[string]string) {
 		},
 	})
 	if err != nil {
-		fmt.Println(err.Error())
+		errs.add("ec2:DescribeInstances", err)
 	} else {
 		count := 0
 		for i := 0; i < len(respDescribeInstances.Reservations); i++ {
 			count += len(respDescribeInstances.Reservations[i].Instances)
 		}
 		if count > 0 {
-			ec2Usage["Running Instances"] = strconv.Itoa(count)
+			ec2Usage.add("Running Instances", float64(count), report.Count)
 		}
 	}
 
 	// Get volumes
-	respDescribeVolumes, err := svc.DescribeVolumes(&ec2.DescribeVolumesInput{})
+	respDescribeVolumes, err := svc.DescribeVolumesWithContext(ctx, &ec2.DescribeVolumesInput{})
 	if err != nil {
-		fmt.Println(err.Error())
+		errs.add("ec2:DescribeVolumes", err)
 	} else {
 		count := len(respDescribeVolumes.Volumes)
 		if count > 0 {
-			ec2Usage["EBS Volumes"] = strconv.Itoa(count)
+			ec2Usage.add("EBS Volumes", float64(count), report.Count)
 		}
 	}
 
-	// Get AMIs
-	respDescribeImages, err := svc.DescribeImages(&ec2.DescribeImagesInput{
-		Owners: aws.StringSlice([]string{os.Getenv("AWS_ACCOUNT_ID")}),
+	// Get AMIs, owned by the account of the credentials
+	respDescribeImages, err := svc.DescribeImagesWithContext(ctx, &ec2.DescribeImagesInput{
+		Owners: aws.StringSlice([]string{"self"}),
 	})
 	if err != nil {
-		fmt.Println(err.Error())
+		errs.add("ec2:DescribeImages", err)
 	} else {
 		count := len(respDescribeImages.Images)
 		if count > 0 {
-			ec2Usage["AMI Images"] = strconv.Itoa(count)
+			ec2Usage.add("AMI Images", float64(count), report.Count)
 		}
 	}
 
-	// Get Snapshots
-	respDescribeSnapshots, err := svc.DescribeSnapshots(&ec2.DescribeSnapshotsInput{
-		OwnerIds: aws.StringSlice([]string{os.Getenv("AWS_ACCOUNT_ID")}),
+	// Get Snapshots, owned by the account of the credentials
+	respDescribeSnapshots, err := svc.DescribeSnapshotsWithContext(ctx, &ec2.DescribeSnapshotsInput{
+		OwnerIds: aws.StringSlice([]string{"self"}),
 	})
 	if err != nil {
-		fmt.Println(err.Error())
+		errs.add("ec2:DescribeSnapshots", err)
 	} else {
 		count := len(respDescribeSnapshots.Snapshots)
 		if count > 0 {
-			ec2Usage["Snapshots"] = strconv.Itoa(count)
+			ec2Usage.add("Snapshots", float64(count), report.Count)
 		}
 	}
 
 	// Get EIPs
-	respDescribeAddresses, err := svc.DescribeAddresses(&ec2.DescribeAddressesInput{})
+	respDescribeAddresses, err := svc.DescribeAddressesWithContext(ctx, &ec2.DescribeAddressesInput{})
 	if err != nil {
-		fmt.Println(err.Error())
+		errs.add("ec2:DescribeAddresses", err)
 	} else {
 		count := len(respDescribeAddresses.Addresses)
 		if count > 0 {
-			ec2Usage["Elastic IPs"] = strconv.Itoa(count)
+			ec2Usage.add("Elastic IPs", float64(count), report.Count)
+		}
+		unattached := 0
+		for _, address := range respDescribeAddresses.Addresses {
+			if address.AssociationId == nil {
+				unattached++
+			}
+		}
+		if unattached > 0 {
+			ec2Usage.add("Unattached Elastic IPs", float64(unattached), report.Count)
 		}
 	}
 
 	elbSVC := elb.New(sess)
-	respDescribeLoadBalancers, err := elbSVC.DescribeLoadBalancers(&elb.DescribeLoadBalancersInput{})
+	respDescribeLoadBalancers, err := elbSVC.DescribeLoadBalancersWithContext(ctx, &elb.DescribeLoadBalancersInput{})
 	if err != nil {
-		fmt.Println(err.Error())
+		errs.add("elasticloadbalancing:DescribeLoadBalancers", err)
 	} else {
 		count := len(respDescribeLoadBalancers.LoadBalancerDescriptions)
 		if count > 0 {
-			ec2Usage["Load Balancers"] = strconv.Itoa(count)
+			ec2Usage.add("Load Balancers", float64(count), report.Count)
 		}
 	}
 
-	return ec2Usage
+	return ec2Usage, errs.err()
 }
@@ -1,40 +1,49 @@
 package stats
 
 import (
-	"fmt"
-	"strconv"
-	"time"
+	"context"
 
+	"github.com/WUMUXIAN/aws-slack-bot/report"
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/cloudwatch"
 	"github.com/aws/aws-sdk-go/service/elasticache"
 )
 
-// GetElasticacheUsage gets elasticache usage for given sessions within specified period of time.
-func GetElasticacheUsage(sess *session.Session, startTime, endTime time.Time) (elasticacheUsage map[string]string) {
-	elasticacheUsage = make(map[string]string)
+type elasticacheCollector struct{}
+
+func (elasticacheCollector) Name() string       { return "elasticache" }
+func (elasticacheCollector) Title() string      { return "Elasticache Usage" }
+func (elasticacheCollector) Scope() Scope       { return RegionalScope }
+func (elasticacheCollector) ToDate() bool       { return false }
+func (elasticacheCollector) BillingMonth() bool { return false }
+
+// Collect gets elasticache usage for given sessions within specified period of time.
+func (elasticacheCollector) Collect(ctx context.Context, sess *session.Session, period report.Period) ([]report.Metric, error) {
+	startTime, endTime := period.Start, period.End
+	elasticacheUsage := make(metrics, 0)
+	var errs Errors
 
 	svc := elasticache.New(sess)
-	respDescribeReplicationGroups, err := svc.DescribeReplicationGroups(&elasticache.DescribeReplicationGroupsInput{})
+	respDescribeReplicationGroups, err := svc.DescribeReplicationGroupsWithContext(ctx, &elasticache.DescribeReplicationGroupsInput{})
 	if err != nil {
-		fmt.Println(err.Error())
+		errs.add("elasticache:DescribeReplicationGroups", err)
 	} else {
 		count := len(respDescribeReplicationGroups.ReplicationGroups)
 		if count > 0 {
-			elasticacheUsage["Replication Groups"] = strconv.Itoa(count)
+			elasticacheUsage.add("Replication Groups", float64(count), report.Count)
 		}
 
 	}
 
 	// List clusters
-	respDescribeCacheClusters, err := svc.DescribeCacheClusters(&elasticache.DescribeCacheClustersInput{})
+	respDescribeCacheClusters, err := svc.DescribeCacheClustersWithContext(ctx, &elasticache.DescribeCacheClustersInput{})
 	if err != nil {
-		fmt.Println(err.Error())
+		errs.add("elasticache:DescribeCacheClusters", err)
 	} else {
 		count := len(respDescribeCacheClusters.CacheClusters)
 		if count > 0 {
-			elasticacheUsage["Clusters"] = strconv.Itoa(count)
+			elasticacheUsage.add("Clusters", float64(count), report.Count)
 		}
 		if len(respDescribeCacheClusters.CacheClusters) > 0 {
 			nodes := 0
@@ -42,7 +51,7 @@ func GetElasticacheUsage(sess *session.Session, startTime, endTime time.Time) (e
 				nodes += int(aws.Int64Value(elasticacheCluster.NumCacheNodes))
 			}
 			if nodes > 0 {
-				elasticacheUsage["Nodes"] = strconv.Itoa(nodes)
+				elasticacheUsage.add("Nodes", float64(nodes), report.Count)
 			}
 		}
 
@@ -51,16 +60,18 @@ func GetElasticacheUsage(sess *session.Session, startTime, endTime time.Time) (e
 	svcCloudWatch := cloudwatch.New(sess)
 
 	// Get CPU Usage
-	cpuUsage := getMetricsStatistics(svcCloudWatch, startTime, endTime, aws.String("AWS/ElastiCache"), aws.String("CPUUtilization"), "Average", []*cloudwatch.Dimension{})[0]
-	if cpuUsage > 0 {
-		elasticacheUsage["CPU"] = fmt.Sprintf("%0.2f%%", cpuUsage)
+	stats, err := getMetricsStatistics(ctx, svcCloudWatch, startTime, endTime, aws.String("AWS/ElastiCache"), aws.String("CPUUtilization"), "Average", []*cloudwatch.Dimension{})
+	errs.add("cloudwatch:GetMetricStatistics", err)
+	if cpuUsage := stats[0]; cpuUsage > 0 {
+		elasticacheUsage.add("CPU", cpuUsage, report.Percent)
 	}
 
 	// Get Queries
-	bytes := getMetricsStatistics(svcCloudWatch, startTime, endTime, aws.String("AWS/ElastiCache"), aws.String("BytesUsedForCache"), "Average", []*cloudwatch.Dimension{})[0]
-	if bytes > 0 {
-		elasticacheUsage["Cache Size"] = formatStorage(bytes)
+	stats, err = getMetricsStatistics(ctx, svcCloudWatch, startTime, endTime, aws.String("AWS/ElastiCache"), aws.String("BytesUsedForCache"), "Average", []*cloudwatch.Dimension{})
+	errs.add("cloudwatch:GetMetricStatistics", err)
+	if bytes := stats[0]; bytes > 0 {
+		elasticacheUsage.add("Cache Size", bytes, report.Bytes)
 	}
 
-	return elasticacheUsage
+	return elasticacheUsage, errs.err()
 }
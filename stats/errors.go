@@ -0,0 +1,88 @@
+package stats
+
+import (
+	"context"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+)
+
+// The codes problems are classified by.
+const (
+	CodeAccessDenied = "AccessDenied"
+	CodeThrottling   = "Throttling"
+	CodeTimeout      = "Timeout"
+	CodeUnknown      = "Unknown"
+)
+
+// APIError records the failure of an AWS API call made by a collector.
+type APIError struct {
+	API string
+	Err error
+}
+
+func (e APIError) Error() string {
+	return e.API + ": " + e.Err.Error()
+}
+
+// Code classifies the failure as access denied, throttling or timeout, or returns the AWS error code otherwise.
+func (e APIError) Code() string {
+	if e.Err == context.DeadlineExceeded || e.Err == context.Canceled {
+		return CodeTimeout
+	}
+	aerr, ok := e.Err.(awserr.Error)
+	if !ok {
+		return CodeUnknown
+	}
+	switch code := aerr.Code(); {
+	case strings.Contains(code, "AccessDenied"), code == "UnauthorizedOperation", code == "AuthFailure", code == "AuthorizationError":
+		return CodeAccessDenied
+	case strings.Contains(code, "Throttl"), code == "RequestLimitExceeded", code == "TooManyRequestsException", code == "SlowDown":
+		return CodeThrottling
+	case code == request.CanceledErrorCode, code == request.ErrCodeResponseTimeout:
+		return CodeTimeout
+	default:
+		return code
+	}
+}
+
+// Message returns the reason of the failure without the AWS error code.
+func (e APIError) Message() string {
+	if aerr, ok := e.Err.(awserr.Error); ok {
+		return aerr.Message()
+	}
+	return e.Err.Error()
+}
+
+// Errors lists the API calls that failed while a collector gathered usage.
+type Errors []APIError
+
+func (e Errors) Error() string {
+	messages := make([]string, 0, len(e))
+	for _, err := range e {
+		messages = append(messages, err.Error())
+	}
+	return strings.Join(messages, "; ")
+}
+
+// add records the failure of an API call, nil errors and repeated failures of the same call are ignored.
+func (e *Errors) add(api string, err error) {
+	if err == nil {
+		return
+	}
+	for _, recorded := range *e {
+		if recorded.API == api && recorded.Err.Error() == err.Error() {
+			return
+		}
+	}
+	*e = append(*e, APIError{API: api, Err: err})
+}
+
+// err returns the recorded failures as an error, or nil if there is none.
+func (e Errors) err() error {
+	if len(e) == 0 {
+		return nil
+	}
+	return e
+}
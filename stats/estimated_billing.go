@@ -1,23 +1,93 @@
 package stats
 
 import (
+	"context"
 	"encoding/json"
-	"fmt"
 	"sort"
 	"time"
 
+	"github.com/WUMUXIAN/aws-slack-bot/forecast"
+	"github.com/WUMUXIAN/aws-slack-bot/report"
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/cloudwatch"
 )
 
-// GetEstimatedBilling calculates estimated billing for given session within specified period of time
-func GetEstimatedBilling(sess *session.Session, startTime, endTime time.Time) (latest float64, average float64) {
+type billingCollector struct{}
+
+func (billingCollector) Name() string       { return "billing" }
+func (billingCollector) Title() string      { return "Estimated Billing" }
+func (billingCollector) Scope() Scope       { return GlobalScope }
+func (billingCollector) ToDate() bool       { return true }
+func (billingCollector) BillingMonth() bool { return true }
+
+// Collect gets the estimated billing of the given period and the month before it,
+// and forecasts the charges at the end of the period compared with the month before.
+func (billingCollector) Collect(ctx context.Context, sess *session.Session, period report.Period) ([]report.Metric, error) {
 	svc := cloudwatch.New(sess)
+	billing := make(metrics, 0)
+	var errs Errors
+
+	charges, err := getEstimatedCharges(ctx, svc, period.Start, period.End)
+	if err != nil {
+		errs.add("cloudwatch:GetMetricStatistics", err)
+	} else {
+		latest, average := estimatedBilling(charges)
+		billing.add("Daily Average This Month", average, report.USD)
+		billing.add("Accumulated This Month", latest, report.USD)
+	}
 
-	// fmt.Println("Start time: ", startTime)
-	// fmt.Println("End time: ", endTime)
+	lastMonth := period.Previous()
+	lastCharges, err := getEstimatedCharges(ctx, svc, lastMonth.Start, lastMonth.End)
+	if err != nil {
+		errs.add("cloudwatch:GetMetricStatistics", err)
+	} else {
+		latest, average := estimatedBilling(lastCharges)
+		billing = append(billing,
+			report.Metric{Name: "Daily Average Last Month", Value: average, Unit: report.USD, Period: lastMonth},
+			report.Metric{Name: "Accumulated Last Month", Value: latest, Unit: report.USD, Period: lastMonth},
+		)
+	}
+
+	if len(charges) > 0 {
+		billing = append(billing, forecastMetrics(charges, lastCharges, period, time.Now())...)
+	}
+	return billing, errs.err()
+}
 
+// forecastMetrics forecasts the charges at the end of the period from its charges so far, with their confidence band,
+// compared with the charges of the month before if any.
+func forecastMetrics(charges, lastCharges datapoints, period report.Period, now time.Time) []report.Metric {
+	values := make([]float64, 0, len(charges))
+	for i := len(charges) - 1; i >= 0; i-- {
+		values = append(values, charges[i].Maximum)
+	}
+	length := period.End.Add(time.Second).Sub(period.Start)
+	elapsed := now.Sub(period.Start)
+	if elapsed > length {
+		elapsed = length
+	}
+	f, ok := forecast.Month(values, elapsed.Hours()/24, length.Hours()/24)
+	if !ok {
+		return nil
+	}
+
+	forecasts := []report.Metric{
+		{Name: "Linear Forecast This Month", Value: f.Linear, Unit: report.USD},
+		{Name: "Trend Forecast This Month", Value: f.Trend, Unit: report.USD},
+	}
+	for i := range forecasts {
+		low, high := f.Band(forecasts[i].Value)
+		forecasts[i].Band = &report.Band{Low: low, High: high}
+		if len(lastCharges) > 0 {
+			forecasts[i].Changes = []report.Change{{Since: report.SinceLastMonth, Value: lastCharges[0].Maximum, At: period.Start}}
+		}
+	}
+	return forecasts
+}
+
+// getEstimatedCharges gets the month to date charges of each day within specified period of time, latest first.
+func getEstimatedCharges(ctx context.Context, svc *cloudwatch.CloudWatch, startTime, endTime time.Time) (datapoints, error) {
 	params := &cloudwatch.GetMetricStatisticsInput{
 		Namespace:  aws.String("AWS/Billing"),
 		StartTime:  aws.Time(startTime),
@@ -35,11 +105,10 @@ func GetEstimatedBilling(sess *session.Session, startTime, endTime time.Time) (l
 		},
 	}
 
-	resp, err := svc.GetMetricStatistics(params)
+	resp, err := svc.GetMetricStatisticsWithContext(ctx, params)
 
 	if err != nil {
-		fmt.Println(err.Error())
-		return 0, 0
+		return nil, err
 	}
 
 	jsonBody, _ := json.Marshal(resp)
@@ -47,13 +116,74 @@ func GetEstimatedBilling(sess *session.Session, startTime, endTime time.Time) (l
 	var result result
 	json.Unmarshal(jsonBody, &result)
 	sort.Sort(result.Datapoints)
+	return result.Datapoints, nil
+}
 
-	// fmt.Println("Billing Datapoints:", result.Datapoints)
-
-	if len(result.Datapoints) > 0 {
-		latest = result.Datapoints[0].Maximum
-		length := len(result.Datapoints)
-		average = result.Datapoints[0].Maximum / float64(length)
+// estimatedBilling calculates the latest charges and the daily average from the charges of each day, latest first.
+func estimatedBilling(charges datapoints) (latest float64, average float64) {
+	if len(charges) > 0 {
+		latest = charges[0].Maximum
+		average = charges[0].Maximum / float64(len(charges))
 	}
 	return
 }
+
+// DailySpend is the estimated spend of a day.
+type DailySpend struct {
+	Day   time.Time
+	Spend float64
+}
+
+// GetDailySpend gets the estimated spend of each day from start to end, the days starting at midnight in the location.
+// The spend of a day adds up the hourly increments of the month to date EstimatedCharges, which reset when a month starts.
+// Days without any datapoint are left out rather than taken for days without spend.
+func GetDailySpend(ctx context.Context, sess *session.Session, start, end time.Time, location *time.Location) ([]DailySpend, error) {
+	resp, err := cloudwatch.New(sess).GetMetricStatisticsWithContext(ctx, &cloudwatch.GetMetricStatisticsInput{
+		Namespace:  aws.String("AWS/Billing"),
+		StartTime:  aws.Time(start),
+		EndTime:    aws.Time(end),
+		MetricName: aws.String("EstimatedCharges"),
+		Period:     aws.Int64(3600),
+		Statistics: []*string{aws.String("Maximum")},
+		Dimensions: []*cloudwatch.Dimension{
+			{
+				Name:  aws.String("Currency"),
+				Value: aws.String("USD"),
+			},
+		},
+	})
+	if err != nil {
+		return nil, Errors{{API: "cloudwatch:GetMetricStatistics", Err: err}}
+	}
+	return dailySpend(resp.Datapoints, location), nil
+}
+
+// dailySpend adds the increments of the hourly EstimatedCharges datapoints up by day, the days starting at midnight in the location.
+func dailySpend(points []*cloudwatch.Datapoint, location *time.Location) []DailySpend {
+	sort.Slice(points, func(i, j int) bool {
+		return points[i].Timestamp.Before(*points[j].Timestamp)
+	})
+
+	days := make([]DailySpend, 0)
+	previous := 0.0
+	for i, point := range points {
+		charges := aws.Float64Value(point.Maximum)
+		increment := charges - previous
+		if charges < previous {
+			// The charges were reset when the month started.
+			increment = charges
+		}
+		previous = charges
+		if i == 0 {
+			// The first increment is unknown, the datapoint only sets where the charges start from.
+			continue
+		}
+		year, month, day := point.Timestamp.In(location).Date()
+		midnight := time.Date(year, month, day, 0, 0, 0, 0, location)
+		if len(days) == 0 || !days[len(days)-1].Day.Equal(midnight) {
+			days = append(days, DailySpend{Day: midnight})
+		}
+		days[len(days)-1].Spend += increment
+	}
+	return days
+}
@@ -0,0 +1,56 @@
+package stats
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudwatch"
+)
+
+func TestDailySpend(t *testing.T) {
+	singapore := time.FixedZone("SGT", 8*3600)
+	point := func(hour string, charges float64) *cloudwatch.Datapoint {
+		timestamp, err := time.Parse("2006-01-02 15:04", hour)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return &cloudwatch.Datapoint{Timestamp: aws.Time(timestamp), Maximum: aws.Float64(charges)}
+	}
+	// The datapoints are out of order, the charges reset when the month starts in UTC
+	// and there is no datapoint on October 2nd in Singapore.
+	points := []*cloudwatch.Datapoint{
+		point("2026-09-30 23:00", 120),
+		point("2026-09-30 14:00", 100),
+		point("2026-10-01 00:00", 3),
+		point("2026-09-30 15:00", 102),
+		point("2026-10-01 05:00", 5),
+		point("2026-10-02 17:00", 9),
+	}
+	days := dailySpend(points, singapore)
+
+	want := []DailySpend{
+		{Day: time.Date(2026, 9, 30, 0, 0, 0, 0, singapore), Spend: 2},
+		{Day: time.Date(2026, 10, 1, 0, 0, 0, 0, singapore), Spend: 18 + 3 + 2},
+		{Day: time.Date(2026, 10, 3, 0, 0, 0, 0, singapore), Spend: 4},
+	}
+	if len(days) != len(want) {
+		t.Fatalf("got %d days %v, want %d", len(days), days, len(want))
+	}
+	for i := range want {
+		if !days[i].Day.Equal(want[i].Day) || days[i].Spend != want[i].Spend {
+			t.Errorf("got day %d %s spending %f, want %s spending %f", i, days[i].Day, days[i].Spend, want[i].Day, want[i].Spend)
+		}
+	}
+}
+
+func TestDailySpendNoDatapoints(t *testing.T) {
+	if days := dailySpend(nil, time.UTC); len(days) != 0 {
+		t.Errorf("got %d days, want none", len(days))
+	}
+	// A single datapoint only sets where the charges start from.
+	point := &cloudwatch.Datapoint{Timestamp: aws.Time(time.Now()), Maximum: aws.Float64(10)}
+	if days := dailySpend([]*cloudwatch.Datapoint{point}, time.UTC); len(days) != 0 {
+		t.Errorf("got %d days, want none", len(days))
+	}
+}
@@ -0,0 +1,167 @@
+package stats
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/organizations"
+)
+
+// The statuses of the member accounts of an organization.
+const (
+	AccountActive    = organizations.AccountStatusActive
+	AccountSuspended = organizations.AccountStatusSuspended
+)
+
+// OrganizationAccount is a member account of an organization.
+type OrganizationAccount struct {
+	ID     string
+	Name   string
+	Status string
+	// Management is set on the management account of the organization, whose credentials list the accounts.
+	Management bool
+	// Selected is set on the accounts the filter selects, the others being listed as members only.
+	Selected bool
+	// Unknown is set on the accounts the filter couldn't be applied to, as their organizational units or tags
+	// couldn't be listed. They aren't selected.
+	Unknown bool
+}
+
+// OrganizationFilter selects the member accounts of an organization,
+// those in any of the organizational units, nested ones included, and with all the tags.
+// Empty fields select any account.
+type OrganizationFilter struct {
+	OUs  []string
+	Tags map[string]string
+}
+
+// GetOrganizationAccounts lists all the member accounts of the organization of the session's management account,
+// suspended ones included, marking those selected by the filter.
+// If the filter fails on some accounts, they are returned as unknown along with the error.
+func GetOrganizationAccounts(ctx context.Context, sess *session.Session, filter OrganizationFilter) ([]OrganizationAccount, error) {
+	svc := organizations.New(sess)
+	var errs Errors
+
+	organization, err := svc.DescribeOrganizationWithContext(ctx, &organizations.DescribeOrganizationInput{})
+	if err != nil {
+		errs.add("organizations:DescribeOrganization", err)
+		return nil, errs.err()
+	}
+	management := aws.StringValue(organization.Organization.MasterAccountId)
+
+	members := make([]*organizations.Account, 0)
+	err = svc.ListAccountsPagesWithContext(ctx, &organizations.ListAccountsInput{}, func(page *organizations.ListAccountsOutput, lastPage bool) bool {
+		members = append(members, page.Accounts...)
+		return true
+	})
+	if err != nil {
+		errs.add("organizations:ListAccounts", err)
+		return nil, errs.err()
+	}
+
+	// The accounts under the organizational units, nil if any account is.
+	// Those not found are unknown rather than not selected if an organizational unit couldn't be listed.
+	var under map[string]bool
+	unlisted := false
+	if len(filter.OUs) > 0 {
+		under = make(map[string]bool)
+		for _, ou := range filter.OUs {
+			found, err := listAccountsUnder(ctx, svc, ou)
+			if err != nil {
+				errs = append(errs, err.(Errors)...)
+				unlisted = true
+				continue
+			}
+			for _, account := range found {
+				under[aws.StringValue(account.Id)] = true
+			}
+		}
+	}
+
+	accounts := make([]OrganizationAccount, 0, len(members))
+	for _, member := range members {
+		id := aws.StringValue(member.Id)
+		selected := under == nil || under[id]
+		unknown := !selected && unlisted
+		if selected && len(filter.Tags) > 0 {
+			tags, err := listTags(ctx, svc, id)
+			if err != nil {
+				errs.add("organizations:ListTagsForResource", err)
+			}
+			unknown = err != nil
+			selected = err == nil && matchTags(tags, filter.Tags)
+		}
+		accounts = append(accounts, OrganizationAccount{
+			ID:         id,
+			Name:       aws.StringValue(member.Name),
+			Status:     aws.StringValue(member.Status),
+			Management: id == management,
+			Selected:   selected,
+			Unknown:    unknown,
+		})
+	}
+	return accounts, errs.err()
+}
+
+// listAccountsUnder lists the accounts of an organizational unit and of the units nested in it.
+func listAccountsUnder(ctx context.Context, svc *organizations.Organizations, parent string) ([]*organizations.Account, error) {
+	var errs Errors
+	accounts := make([]*organizations.Account, 0)
+	err := svc.ListAccountsForParentPagesWithContext(ctx, &organizations.ListAccountsForParentInput{ParentId: aws.String(parent)},
+		func(page *organizations.ListAccountsForParentOutput, lastPage bool) bool {
+			accounts = append(accounts, page.Accounts...)
+			return true
+		})
+	if err != nil {
+		errs.add("organizations:ListAccountsForParent", err)
+		return nil, errs
+	}
+
+	units := make([]string, 0)
+	err = svc.ListOrganizationalUnitsForParentPagesWithContext(ctx, &organizations.ListOrganizationalUnitsForParentInput{ParentId: aws.String(parent)},
+		func(page *organizations.ListOrganizationalUnitsForParentOutput, lastPage bool) bool {
+			for _, unit := range page.OrganizationalUnits {
+				units = append(units, aws.StringValue(unit.Id))
+			}
+			return true
+		})
+	if err != nil {
+		errs.add("organizations:ListOrganizationalUnitsForParent", err)
+		return nil, errs
+	}
+	for _, unit := range units {
+		nested, err := listAccountsUnder(ctx, svc, unit)
+		if err != nil {
+			return nil, err
+		}
+		accounts = append(accounts, nested...)
+	}
+	return accounts, nil
+}
+
+// listTags lists the tags of an account.
+func listTags(ctx context.Context, svc *organizations.Organizations, id string) (map[string]string, error) {
+	tags := make(map[string]string)
+	err := svc.ListTagsForResourcePagesWithContext(ctx, &organizations.ListTagsForResourceInput{ResourceId: aws.String(id)},
+		func(page *organizations.ListTagsForResourceOutput, lastPage bool) bool {
+			for _, t := range page.Tags {
+				tags[aws.StringValue(t.Key)] = aws.StringValue(t.Value)
+			}
+			return true
+		})
+	if err != nil {
+		return nil, err
+	}
+	return tags, nil
+}
+
+// matchTags reports whether the tags hold all the wanted ones.
+func matchTags(tags, wanted map[string]string) bool {
+	for key, value := range wanted {
+		if tags[key] != value {
+			return false
+		}
+	}
+	return true
+}
@@ -1,69 +1,82 @@
 package stats
 
 import (
-	"fmt"
-	"strconv"
-	"time"
+	"context"
 
+	"github.com/WUMUXIAN/aws-slack-bot/report"
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/cloudwatch"
 	"github.com/aws/aws-sdk-go/service/rds"
 )
 
-// GetRDSUsage gets RDS usage for given sessions within specified period of time.
-func GetRDSUsage(sess *session.Session, startTime, endTime time.Time) (RDSUsage map[string]string) {
-	RDSUsage = make(map[string]string)
+type rdsCollector struct{}
+
+func (rdsCollector) Name() string       { return "rds" }
+func (rdsCollector) Title() string      { return "RDS Usage" }
+func (rdsCollector) Scope() Scope       { return RegionalScope }
+func (rdsCollector) ToDate() bool       { return false }
+func (rdsCollector) BillingMonth() bool { return false }
+
+// Collect gets RDS usage for given sessions within specified period of time.
+func (rdsCollector) Collect(ctx context.Context, sess *session.Session, period report.Period) ([]report.Metric, error) {
+	startTime, endTime := period.Start, period.End
+	RDSUsage := make(metrics, 0)
+	var errs Errors
 
 	svc := rds.New(sess)
 
 	// List clusters
-	respDescribeDBClusters, err := svc.DescribeDBClusters(&rds.DescribeDBClustersInput{})
+	respDescribeDBClusters, err := svc.DescribeDBClustersWithContext(ctx, &rds.DescribeDBClustersInput{})
 	if err != nil {
-		fmt.Println(err.Error())
+		errs.add("rds:DescribeDBClusters", err)
 	} else {
 		count := len(respDescribeDBClusters.DBClusters)
 		if count > 0 {
-			RDSUsage["Clusters"] = strconv.Itoa(count)
+			RDSUsage.add("Clusters", float64(count), report.Count)
 		}
 	}
 
 	// List DB Instances
-	respDescribeDBInstances, err := svc.DescribeDBInstances(&rds.DescribeDBInstancesInput{})
+	respDescribeDBInstances, err := svc.DescribeDBInstancesWithContext(ctx, &rds.DescribeDBInstancesInput{})
 	if err != nil {
-		fmt.Println(err.Error())
+		errs.add("rds:DescribeDBInstances", err)
 	} else {
 		count := len(respDescribeDBInstances.DBInstances)
 		if count > 0 {
-			RDSUsage["Instances"] = strconv.Itoa(count)
+			RDSUsage.add("Instances", float64(count), report.Count)
 		}
 	}
 
 	svcCloudWatch := cloudwatch.New(sess)
 
 	// Get CPU Usage
-	cpuUsage := getMetricsStatistics(svcCloudWatch, startTime, endTime, aws.String("AWS/RDS"), aws.String("CPUUtilization"), "Average", []*cloudwatch.Dimension{})[0]
-	if cpuUsage > 0 {
-		RDSUsage["CPU"] = fmt.Sprintf("%0.2f%%", cpuUsage)
+	stats, err := getMetricsStatistics(ctx, svcCloudWatch, startTime, endTime, aws.String("AWS/RDS"), aws.String("CPUUtilization"), "Average", []*cloudwatch.Dimension{})
+	errs.add("cloudwatch:GetMetricStatistics", err)
+	if cpuUsage := stats[0]; cpuUsage > 0 {
+		RDSUsage.add("CPU", cpuUsage, report.Percent)
 	}
 
 	// Get Queries
-	queries := getMetricsStatistics(svcCloudWatch, startTime, endTime, aws.String("AWS/RDS"), aws.String("Queries"), "Average", []*cloudwatch.Dimension{})[0]
-	if queries > 0 {
-		RDSUsage["Queries"] = fmt.Sprintf("%0.2f/Second", queries)
+	stats, err = getMetricsStatistics(ctx, svcCloudWatch, startTime, endTime, aws.String("AWS/RDS"), aws.String("Queries"), "Average", []*cloudwatch.Dimension{})
+	errs.add("cloudwatch:GetMetricStatistics", err)
+	if queries := stats[0]; queries > 0 {
+		RDSUsage.add("Queries", queries, report.CountPerSecond)
 	}
 
 	// Get NetworkThroughput
-	throughput := getMetricsStatistics(svcCloudWatch, startTime, endTime, aws.String("AWS/RDS"), aws.String("NetworkThroughput"), "Average", []*cloudwatch.Dimension{})[0]
-	if throughput > 0 {
-		RDSUsage["NetworkThroughput"] = fmt.Sprintf("%s/Second", formatStorage(throughput))
+	stats, err = getMetricsStatistics(ctx, svcCloudWatch, startTime, endTime, aws.String("AWS/RDS"), aws.String("NetworkThroughput"), "Average", []*cloudwatch.Dimension{})
+	errs.add("cloudwatch:GetMetricStatistics", err)
+	if throughput := stats[0]; throughput > 0 {
+		RDSUsage.add("NetworkThroughput", throughput, report.BytesPerSecond)
 	}
 
 	// Get Deadlocks
-	deadlocks := getMetricsStatistics(svcCloudWatch, startTime, endTime, aws.String("AWS/RDS"), aws.String("Deadlocks"), "Average", []*cloudwatch.Dimension{})[0]
-	if deadlocks > 0 {
-		RDSUsage["Deadlocks"] = fmt.Sprintf("%0.2f/Second", deadlocks)
+	stats, err = getMetricsStatistics(ctx, svcCloudWatch, startTime, endTime, aws.String("AWS/RDS"), aws.String("Deadlocks"), "Average", []*cloudwatch.Dimension{})
+	errs.add("cloudwatch:GetMetricStatistics", err)
+	if deadlocks := stats[0]; deadlocks > 0 {
+		RDSUsage.add("Deadlocks", deadlocks, report.CountPerSecond)
 	}
 
-	return RDSUsage
+	return RDSUsage, errs.err()
 }
@@ -0,0 +1,88 @@
+package stats
+
+import (
+	"fmt"
+	"strings"
+)
+
+var registry = make([]Collector, 0)
+
+// optional are the names of the collectors only enabled by name.
+var optional = make(map[string]bool)
+
+func init() {
+	// The registration order is the order sections appear in the report.
+	Register(ec2Collector{})
+	Register(s3Collector{})
+	Register(cloudFrontCollector{})
+	Register(rdsCollector{})
+	Register(elasticacheCollector{})
+	Register(billingCollector{})
+	Register(serviceChargesCollector{})
+	// Cost Explorer charges for every request.
+	RegisterOptional(costExplorerCollector{cost: UnblendedCost, groupBy: []string{ByService}})
+	RegisterOptional(tagCostsCollector{cost: UnblendedCost})
+	RegisterOptional(commitmentsCollector{expiringDays: 30})
+}
+
+// Register makes a collector available by its name, it panics if the name is already taken.
+func Register(collector Collector) {
+	if _, ok := Lookup(collector.Name()); ok {
+		panic("stats: collector registered twice: " + collector.Name())
+	}
+	registry = append(registry, collector)
+}
+
+// RegisterOptional makes a collector available by its name, like Register,
+// but only enabled when named rather than along with all the others.
+func RegisterOptional(collector Collector) {
+	Register(collector)
+	optional[collector.Name()] = true
+}
+
+// Lookup finds the registered collector with given name.
+func Lookup(name string) (Collector, bool) {
+	for _, collector := range registry {
+		if collector.Name() == name {
+			return collector, true
+		}
+	}
+	return nil, false
+}
+
+// Names returns the names of all registered collectors in registration order.
+func Names() []string {
+	names := make([]string, 0, len(registry))
+	for _, collector := range registry {
+		names = append(names, collector.Name())
+	}
+	return names
+}
+
+// Select returns the collectors enabled by given names in registration order.
+// All registered collectors but the optional ones are returned if no name is given.
+func Select(names []string) ([]Collector, error) {
+	if len(names) == 0 {
+		collectors := make([]Collector, 0, len(registry))
+		for _, collector := range registry {
+			if !optional[collector.Name()] {
+				collectors = append(collectors, collector)
+			}
+		}
+		return collectors, nil
+	}
+	enabled := make(map[string]bool)
+	for _, name := range names {
+		if _, ok := Lookup(name); !ok {
+			return nil, fmt.Errorf("unknown collector %q, available collectors are: %s", name, strings.Join(Names(), ","))
+		}
+		enabled[name] = true
+	}
+	collectors := make([]Collector, 0, len(enabled))
+	for _, collector := range registry {
+		if enabled[collector.Name()] {
+			collectors = append(collectors, collector)
+		}
+	}
+	return collectors, nil
+}
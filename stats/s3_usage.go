@@ -1,26 +1,37 @@
 package stats
 
 import (
-	"fmt"
-	"time"
+	"context"
+	"sort"
 
+	"github.com/WUMUXIAN/aws-slack-bot/report"
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/cloudwatch"
 	"github.com/aws/aws-sdk-go/service/s3"
 )
 
-// GetS3Usage gets the S3 usage for given session within specified period of time.
-func GetS3Usage(sess *session.Session, startTime, endTime time.Time) (s3Usage map[string]string) {
-	s3Usage = make(map[string]string)
+type s3Collector struct{}
+
+func (s3Collector) Name() string       { return "s3" }
+func (s3Collector) Title() string      { return "S3 Usage" }
+func (s3Collector) Scope() Scope       { return RegionalScope }
+func (s3Collector) ToDate() bool       { return false }
+func (s3Collector) BillingMonth() bool { return false }
+
+// Collect gets the S3 usage for given session within specified period of time.
+func (s3Collector) Collect(ctx context.Context, sess *session.Session, period report.Period) ([]report.Metric, error) {
+	startTime, endTime := period.Start, period.End
+	s3Usage := make(metrics, 0)
+	var errs Errors
 
 	svc := s3.New(sess)
 
 	// List buckets
 	buckets := make([]string, 0)
-	respListBuckets, err := svc.ListBuckets(&s3.ListBucketsInput{})
+	respListBuckets, err := svc.ListBucketsWithContext(ctx, &s3.ListBucketsInput{})
 	if err != nil {
-		fmt.Println(err.Error())
+		errs.add("s3:ListBuckets", err)
 	} else {
 		for _, bucket := range respListBuckets.Buckets {
 			buckets = append(buckets, aws.StringValue(bucket.Name))
@@ -34,22 +45,34 @@ func GetS3Usage(sess *session.Session, startTime, endTime time.Time) (s3Usage ma
 		Value: aws.String("StandardStorage"),
 	}
 
+	sort.Strings(buckets)
+	bucketUsage := make(metrics, 0)
 	totalBytes := float64(0)
 	for _, bucket := range buckets {
+		// Stop once timed out, the remaining buckets would fail the same way.
+		if ctx.Err() != nil {
+			break
+		}
 		demensions := []*cloudwatch.Dimension{
 			demensionStandardStorage,
 			{
 				Name:  aws.String("BucketName"),
 				Value: aws.String(bucket),
 			}}
-		sizeInBytes := getMetricsStatistics(svcCloudWatch, startTime, endTime, aws.String("AWS/S3"), aws.String("BucketSizeBytes"), "Sum", demensions)[0]
-		if sizeInBytes > 0 {
-			s3Usage[bucket] = formatStorage(sizeInBytes)
+		stats, err := getMetricsStatistics(ctx, svcCloudWatch, startTime, endTime, aws.String("AWS/S3"), aws.String("BucketSizeBytes"), "Sum", demensions)
+		errs.add("cloudwatch:GetMetricStatistics", err)
+		if sizeInBytes := stats[0]; sizeInBytes > 0 {
+			bucketUsage = append(bucketUsage, report.Metric{
+				Name:       "Bucket Size",
+				Value:      sizeInBytes,
+				Unit:       report.Bytes,
+				Dimensions: map[string]string{"BucketName": bucket},
+			})
 			totalBytes += sizeInBytes
 		}
 	}
 	if totalBytes > 0 {
-		s3Usage["_total size_"] = formatStorage(totalBytes)
+		s3Usage.add("Total Size", totalBytes, report.Bytes)
 	}
-	return s3Usage
+	return append(s3Usage, bucketUsage...), errs.err()
 }
@@ -0,0 +1,168 @@
+package stats
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/WUMUXIAN/aws-slack-bot/report"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/cloudwatch"
+)
+
+// topServices is the number of services listed by their charges, the others being added up.
+const topServices = 10
+
+type serviceChargesCollector struct{}
+
+func (serviceChargesCollector) Name() string       { return "charges" }
+func (serviceChargesCollector) Title() string      { return "Charges by Service" }
+func (serviceChargesCollector) Scope() Scope       { return GlobalScope }
+func (serviceChargesCollector) ToDate() bool       { return true }
+func (serviceChargesCollector) BillingMonth() bool { return true }
+
+// serviceCharges is the month to date charges of a service, this month and last month up to the same day.
+type serviceCharges struct {
+	service   string
+	charges   float64
+	lastMonth *report.Change
+}
+
+// Collect gets the month to date charges of the services that spent the most in the given period,
+// with their share of the charges of all services and their charges last month up to the same day.
+func (serviceChargesCollector) Collect(ctx context.Context, sess *session.Session, period report.Period) ([]report.Metric, error) {
+	svc := cloudwatch.New(sess)
+	var errs Errors
+
+	dimensions := make([][]*cloudwatch.Dimension, 0)
+	err := svc.ListMetricsPagesWithContext(ctx, &cloudwatch.ListMetricsInput{
+		Namespace:  aws.String("AWS/Billing"),
+		MetricName: aws.String("EstimatedCharges"),
+	}, func(page *cloudwatch.ListMetricsOutput, lastPage bool) bool {
+		for _, metric := range page.Metrics {
+			// The charges of a service in each linked account are published too, with a LinkedAccount dimension.
+			if len(metric.Dimensions) == 2 && dimensionValue(metric.Dimensions, "ServiceName") != "" && dimensionValue(metric.Dimensions, "Currency") != "" {
+				dimensions = append(dimensions, metric.Dimensions)
+			}
+		}
+		return true
+	})
+	if err != nil {
+		errs.add("cloudwatch:ListMetrics", err)
+		return nil, errs.err()
+	}
+
+	services := make([]serviceCharges, 0, len(dimensions))
+	total := 0.0
+	for _, dimension := range dimensions {
+		charges, ok, err := getServiceCharges(ctx, svc, dimension, period)
+		if err != nil {
+			errs.add("cloudwatch:GetMetricStatistics", err)
+			continue
+		}
+		if ok && charges.charges > 0 {
+			services = append(services, charges)
+			total += charges.charges
+		}
+	}
+	sort.SliceStable(services, func(i, j int) bool {
+		return services[i].charges > services[j].charges
+	})
+
+	if len(services) > topServices {
+		others := serviceCharges{service: "Other services"}
+		for _, service := range services[topServices:] {
+			others.charges += service.charges
+		}
+		services = append(services[:topServices], others)
+	}
+	charges := make([]report.Metric, 0, len(services))
+	for _, service := range services {
+		metric := report.Metric{
+			Name:       "Charges This Month",
+			Value:      service.charges,
+			Unit:       report.USD,
+			Dimensions: map[string]string{"ServiceName": service.service},
+			Share:      service.charges / total * 100,
+		}
+		if service.lastMonth != nil {
+			metric.Changes = []report.Change{*service.lastMonth}
+		}
+		charges = append(charges, metric)
+	}
+	return charges, errs.err()
+}
+
+// getServiceCharges gets the month to date charges of the service of given dimensions in the period,
+// and in the month before up to the same day if it was charged then.
+// It returns false if the service wasn't charged in the period.
+func getServiceCharges(ctx context.Context, svc *cloudwatch.CloudWatch, dimensions []*cloudwatch.Dimension, period report.Period) (serviceCharges, bool, error) {
+	lastMonth := period.Previous()
+	resp, err := svc.GetMetricStatisticsWithContext(ctx, &cloudwatch.GetMetricStatisticsInput{
+		Namespace:  aws.String("AWS/Billing"),
+		StartTime:  aws.Time(lastMonth.Start),
+		EndTime:    aws.Time(period.End),
+		MetricName: aws.String("EstimatedCharges"),
+		Period:     aws.Int64(86400),
+		Statistics: []*string{aws.String("Maximum")},
+		Dimensions: dimensions,
+	})
+	if err != nil {
+		return serviceCharges{}, false, err
+	}
+	points := resp.Datapoints
+	sort.Slice(points, func(i, j int) bool {
+		return points[i].Timestamp.Before(*points[j].Timestamp)
+	})
+
+	// A day belongs to the month its middle falls in, whatever the daylight saving time.
+	thisMonth := make([]*cloudwatch.Datapoint, 0)
+	previousMonth := make([]*cloudwatch.Datapoint, 0)
+	for _, point := range points {
+		if point.Timestamp.Add(12 * time.Hour).Before(period.Start) {
+			previousMonth = append(previousMonth, point)
+		} else {
+			thisMonth = append(thisMonth, point)
+		}
+	}
+	if len(thisMonth) == 0 {
+		return serviceCharges{}, false, nil
+	}
+
+	charges := serviceCharges{
+		service: dimensionValue(dimensions, "ServiceName"),
+		charges: aws.Float64Value(thisMonth[len(thisMonth)-1].Maximum),
+	}
+	// Last month is matched by the day of the month, its last day if it is shorter, as days may be missing.
+	day := dayOf(thisMonth[len(thisMonth)-1], period)
+	var same *cloudwatch.Datapoint
+	for _, point := range previousMonth {
+		if dayOf(point, period) <= day {
+			same = point
+		}
+	}
+	if same != nil {
+		charges.lastMonth = &report.Change{
+			Since: report.SinceLastMonth,
+			Value: aws.Float64Value(same.Maximum),
+			At:    aws.TimeValue(same.Timestamp),
+		}
+	}
+	return charges, true, nil
+}
+
+// dayOf returns the day of the month of a daily datapoint, the day its middle falls in, in the timezone of the period.
+func dayOf(point *cloudwatch.Datapoint, period report.Period) int {
+	return point.Timestamp.Add(12 * time.Hour).In(period.Start.Location()).Day()
+}
+
+// dimensionValue returns the value of the dimension of given name, empty if there is none.
+func dimensionValue(dimensions []*cloudwatch.Dimension, name string) string {
+	for _, dimension := range dimensions {
+		if aws.StringValue(dimension.Name) == name {
+			return aws.StringValue(dimension.Value)
+		}
+	}
+	return ""
+}
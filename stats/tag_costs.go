@@ -0,0 +1,120 @@
+package stats
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/WUMUXIAN/aws-slack-bot/report"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/costexplorer"
+)
+
+// Untagged is the tag value the cost of the resources without the tag is reported by.
+const Untagged = "(untagged)"
+
+type tagCostsCollector struct {
+	cost            string
+	keys            []string
+	untaggedWarning float64
+}
+
+// NewTagCostsCollector creates the collector reporting the month to date cost of given type, unblended or amortized,
+// grouped by the values of each of the cost allocation tag keys.
+// The untagged cost is marked as a warning once it is more than untaggedWarning percent of the total, 0 meaning never.
+func NewTagCostsCollector(cost string, keys []string, untaggedWarning float64) (Collector, error) {
+	if _, ok := costMetrics[cost]; !ok {
+		return nil, fmt.Errorf("unknown cost %q, available costs are %s, %s", cost, UnblendedCost, AmortizedCost)
+	}
+	for _, key := range keys {
+		if strings.TrimSpace(key) == "" {
+			return nil, fmt.Errorf("the tag key is empty")
+		}
+	}
+	return tagCostsCollector{cost: cost, keys: keys, untaggedWarning: untaggedWarning}, nil
+}
+
+func (tagCostsCollector) Name() string       { return "tags" }
+func (tagCostsCollector) Title() string      { return "Cost by Tag" }
+func (tagCostsCollector) Scope() Scope       { return GlobalScope }
+func (tagCostsCollector) ToDate() bool       { return true }
+func (tagCostsCollector) BillingMonth() bool { return false }
+
+// Collect gets the month to date cost of the given period from Cost Explorer by the values of every tag key,
+// the untagged cost first, then the values that cost the most, with their share of the total.
+func (o tagCostsCollector) Collect(ctx context.Context, sess *session.Session, period report.Period) ([]report.Metric, error) {
+	svc := costexplorer.New(sess)
+	cost := costMetrics[o.cost]
+	start, end := costExplorerPeriod(period, time.Now())
+	costs := make(metrics, 0)
+	var errs Errors
+
+	for _, key := range o.keys {
+		results, err := getCostAndUsage(ctx, svc, start, end, costexplorer.GranularityMonthly, cost.metric,
+			&costexplorer.GroupDefinition{Type: aws.String(costexplorer.GroupDefinitionTypeTag), Key: aws.String(key)})
+		if err != nil {
+			errs.add("ce:GetCostAndUsage", err)
+			continue
+		}
+		values := make(map[string]float64)
+		total := 0.0
+		for _, result := range results {
+			for _, group := range result.Groups {
+				if len(group.Keys) == 0 {
+					continue
+				}
+				value := tagValue(aws.StringValue(group.Keys[0]), key)
+				amount := costAmount(group.Metrics, cost.metric)
+				values[value] += amount
+				total += amount
+			}
+		}
+		costs = append(costs, o.tagCosts(values, total, cost.name+" by "+key, key)...)
+	}
+	return costs, errs.err()
+}
+
+// tagCosts returns the cost of every value of a tag with its share of the total, the untagged cost first,
+// marked if it is above the warning, then the others from the most to the least.
+func (o tagCostsCollector) tagCosts(values map[string]float64, total float64, name, key string) []report.Metric {
+	tagged := make([]string, 0, len(values))
+	for value, cost := range values {
+		if value != Untagged && cost > 0 {
+			tagged = append(tagged, value)
+		}
+	}
+	sort.Slice(tagged, func(i, j int) bool {
+		if values[tagged[i]] != values[tagged[j]] {
+			return values[tagged[i]] > values[tagged[j]]
+		}
+		return tagged[i] < tagged[j]
+	})
+	if values[Untagged] > 0 {
+		tagged = append([]string{Untagged}, tagged...)
+	}
+
+	costs := make([]report.Metric, 0, len(tagged))
+	for _, value := range tagged {
+		metric := report.Metric{Name: name, Value: values[value], Unit: report.USD, Dimensions: map[string]string{key: value}}
+		if total > 0 {
+			metric.Share = values[value] / total * 100
+		}
+		if value == Untagged && o.untaggedWarning > 0 && metric.Share > o.untaggedWarning {
+			metric.Severity = report.Warning
+		}
+		costs = append(costs, metric)
+	}
+	return costs
+}
+
+// tagValue returns the value of a Cost Explorer group key of a tag, "key$value", or Untagged if it is empty.
+func tagValue(group, key string) string {
+	value := strings.TrimPrefix(group, key+"$")
+	if value == "" {
+		return Untagged
+	}
+	return value
+}
@@ -1,8 +1,8 @@
 package stats
 
 import (
+	"context"
 	"encoding/json"
-	"fmt"
 	"sort"
 	"time"
 
@@ -14,18 +14,9 @@ type result struct {
 	Datapoints datapoints
 }
 
-// GetSortedKeySlice gets sorted key slices from a map
-func GetSortedKeySlice(m map[string]string) []string {
-	keys := make([]string, 0)
-	for key := range m {
-		keys = append(keys, key)
-	}
-	sort.Sort(sort.StringSlice(keys))
-	return keys
-}
-
-func getMetricsStatistics(svcCloudWatch *cloudwatch.CloudWatch, startTime, endTime time.Time, nameSpace, metricsName *string, statistics string, demensions []*cloudwatch.Dimension) []float64 {
-	respGetMetricStatistics, err := svcCloudWatch.GetMetricStatistics(&cloudwatch.GetMetricStatisticsInput{
+// getMetricsStatistics gets the daily statistics of a metric, latest first, or a single zero if there is none.
+func getMetricsStatistics(ctx context.Context, svcCloudWatch *cloudwatch.CloudWatch, startTime, endTime time.Time, nameSpace, metricsName *string, statistics string, demensions []*cloudwatch.Dimension) ([]float64, error) {
+	respGetMetricStatistics, err := svcCloudWatch.GetMetricStatisticsWithContext(ctx, &cloudwatch.GetMetricStatisticsInput{
 		Namespace:  nameSpace,
 		StartTime:  aws.Time(startTime),
 		EndTime:    aws.Time(endTime),
@@ -40,7 +31,7 @@ func getMetricsStatistics(svcCloudWatch *cloudwatch.CloudWatch, startTime, endTi
 	stats := make([]float64, 0)
 
 	if err != nil {
-		fmt.Println(err.Error())
+		return []float64{0}, err
 	} else {
 		jsonBody, _ := json.Marshal(respGetMetricStatistics)
 
@@ -63,21 +54,8 @@ func getMetricsStatistics(svcCloudWatch *cloudwatch.CloudWatch, startTime, endTi
 					stats = append(stats, dp.SampleCount)
 				}
 			}
-			return stats
+			return stats, nil
 		}
 	}
-	return []float64{0}
-}
-
-func formatStorage(bytes float64) string {
-	if bytes >= 1024*1024*1024*1024 {
-		return fmt.Sprintf("%0.2f TB", bytes/(1024*1024*1024*1024))
-	} else if bytes > 1024*1024*1024 {
-		return fmt.Sprintf("%0.2f GB", bytes/(1024*1024*1024))
-	} else if bytes > 1024*1024 {
-		return fmt.Sprintf("%0.2f MB", bytes/(1024*1024))
-	} else if bytes > 1024 {
-		return fmt.Sprintf("%0.2f KB", bytes/(1024))
-	}
-	return fmt.Sprintf("%0.2f Bytes", bytes)
+	return []float64{0}, nil
 }